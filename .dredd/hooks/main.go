@@ -21,6 +21,8 @@ var skipTests = []string{
 	//"/api/upgrade > Upgrade the server > 200 > application/json",
 	// TODO - Skipping this while we work out how to get a 204 response from the api for testing
 	//"/api/upgrade > Check if new updates available and fetch /info > 204 > application/json",
+	// test data contains no artifact files
+	"project > /api/project/{project_id}/tasks/{task_id}/artifacts/{artifact_id} > Download the artifact > 200 > application/octet-stream",
 }
 
 // Dredd expects that you have already set up the database and run all migrations before it begins.
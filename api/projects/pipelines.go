@@ -0,0 +1,247 @@
+package projects
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/ansible-semaphore/semaphore/api/helpers"
+	"github.com/ansible-semaphore/semaphore/db"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/context"
+)
+
+// PipelineMiddleware ensures a pipeline exists and loads it to the context
+func PipelineMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		project := context.Get(r, "project").(db.Project)
+		pipelineID, err := helpers.GetIntParam("pipeline_id", w, r)
+		if err != nil {
+			return
+		}
+
+		pipeline, err := helpers.Store(r).GetPipeline(project.ID, pipelineID)
+
+		if err != nil {
+			helpers.WriteError(w, err)
+			return
+		}
+
+		context.Set(r, "pipeline", pipeline)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// PipelineRunMiddleware ensures a run of the pipeline exists and loads it to the context
+func PipelineRunMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pipeline := context.Get(r, "pipeline").(db.Pipeline)
+		runID, err := helpers.GetIntParam("run_id", w, r)
+		if err != nil {
+			return
+		}
+
+		run, err := helpers.Store(r).GetPipelineRun(pipeline.ProjectID, runID)
+
+		if err == nil && run.PipelineID != pipeline.ID {
+			err = db.ErrNotFound
+		}
+
+		if err != nil {
+			helpers.WriteError(w, err)
+			return
+		}
+
+		context.Set(r, "pipeline_run", run)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GetPipelines returns a pipeline of the context or all pipelines of the project
+func GetPipelines(w http.ResponseWriter, r *http.Request) {
+	if pipeline := context.Get(r, "pipeline"); pipeline != nil {
+		helpers.WriteJSON(w, http.StatusOK, pipeline.(db.Pipeline))
+		return
+	}
+
+	project := context.Get(r, "project").(db.Project)
+
+	pipelines, err := helpers.Store(r).GetPipelines(project.ID)
+
+	if err != nil {
+		helpers.WriteError(w, err)
+		return
+	}
+
+	helpers.WriteJSON(w, http.StatusOK, pipelines)
+}
+
+func createPipelineEvent(r *http.Request, pipeline db.Pipeline, desc string) {
+	user := context.Get(r, "user").(*db.User)
+	objType := db.EventPipeline
+
+	_, err := helpers.Store(r).CreateEvent(db.Event{
+		UserID:      &user.ID,
+		ProjectID:   &pipeline.ProjectID,
+		ObjectType:  &objType,
+		ObjectID:    &pipeline.ID,
+		Description: &desc,
+	})
+
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+// AddPipeline adds a new pipeline to the database
+func AddPipeline(w http.ResponseWriter, r *http.Request) {
+	project := context.Get(r, "project").(db.Project)
+	var pipeline db.Pipeline
+
+	if !helpers.Bind(w, r, &pipeline) {
+		return
+	}
+
+	if pipeline.ProjectID != project.ID {
+		helpers.WriteJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "Project ID in body and URL must be the same",
+		})
+		return
+	}
+
+	if err := pipeline.Validate(); err != nil {
+		helpers.WriteError(w, err)
+		return
+	}
+
+	newPipeline, err := helpers.Store(r).CreatePipeline(pipeline)
+
+	if err != nil {
+		helpers.WriteError(w, err)
+		return
+	}
+
+	createPipelineEvent(r, newPipeline, "Pipeline "+newPipeline.Name+" created")
+
+	helpers.WriteJSON(w, http.StatusCreated, newPipeline)
+}
+
+// UpdatePipeline updates the pipeline in the database
+func UpdatePipeline(w http.ResponseWriter, r *http.Request) {
+	oldPipeline := context.Get(r, "pipeline").(db.Pipeline)
+	var pipeline db.Pipeline
+
+	if !helpers.Bind(w, r, &pipeline) {
+		return
+	}
+
+	if pipeline.ID != oldPipeline.ID {
+		helpers.WriteJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "Pipeline ID in URL and in body must be the same",
+		})
+		return
+	}
+
+	pipeline.ProjectID = oldPipeline.ProjectID
+
+	if err := pipeline.Validate(); err != nil {
+		helpers.WriteError(w, err)
+		return
+	}
+
+	if err := helpers.Store(r).UpdatePipeline(pipeline); err != nil {
+		helpers.WriteError(w, err)
+		return
+	}
+
+	createPipelineEvent(r, pipeline, "Pipeline "+pipeline.Name+" updated")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemovePipeline deletes the pipeline and its runs from the database
+func RemovePipeline(w http.ResponseWriter, r *http.Request) {
+	pipeline := context.Get(r, "pipeline").(db.Pipeline)
+
+	err := helpers.Store(r).DeletePipeline(pipeline.ProjectID, pipeline.ID)
+
+	if err != nil {
+		helpers.WriteError(w, err)
+		return
+	}
+
+	createPipelineEvent(r, pipeline, "Pipeline "+pipeline.Name+" deleted")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetPipelineRuns returns runs of the pipeline, newest first
+func GetPipelineRuns(w http.ResponseWriter, r *http.Request) {
+	pipeline := context.Get(r, "pipeline").(db.Pipeline)
+
+	runs, err := helpers.Store(r).GetPipelineRuns(pipeline.ProjectID, pipeline.ID, helpers.QueryParams(r.URL))
+
+	if err != nil {
+		helpers.WriteError(w, err)
+		return
+	}
+
+	helpers.WriteJSON(w, http.StatusOK, runs)
+}
+
+// RunPipeline starts a new run of the pipeline
+func RunPipeline(w http.ResponseWriter, r *http.Request) {
+	pipeline := context.Get(r, "pipeline").(db.Pipeline)
+	user := context.Get(r, "user").(*db.User)
+
+	var params struct {
+		// Environment is JSON of extra variables passed to tasks of all stages.
+		Environment string `json:"environment"`
+	}
+
+	if !helpers.Bind(w, r, &params) {
+		return
+	}
+
+	run, err := helpers.TaskPool(r).StartPipeline(pipeline, params.Environment, &user.ID)
+
+	if err != nil {
+		helpers.WriteError(w, err)
+		return
+	}
+
+	helpers.WriteJSON(w, http.StatusCreated, run)
+}
+
+// GetPipelineRun returns the run with tasks of its stages
+func GetPipelineRun(w http.ResponseWriter, r *http.Request) {
+	run := context.Get(r, "pipeline_run").(db.PipelineRun)
+
+	tasks, err := helpers.Store(r).GetPipelineRunTasks(run.ProjectID, run.ID)
+
+	if err != nil {
+		helpers.WriteError(w, err)
+		return
+	}
+
+	helpers.WriteJSON(w, http.StatusOK, db.PipelineRunWithTasks{
+		PipelineRun: run,
+		Tasks:       tasks,
+	})
+}
+
+// StopPipelineRun stops active tasks of the run and cancels stages which are not started yet
+func StopPipelineRun(w http.ResponseWriter, r *http.Request) {
+	pipeline := context.Get(r, "pipeline").(db.Pipeline)
+	run := context.Get(r, "pipeline_run").(db.PipelineRun)
+
+	err := helpers.TaskPool(r).StopPipelineRun(run)
+
+	if err != nil {
+		helpers.WriteError(w, err)
+		return
+	}
+
+	createPipelineEvent(r, pipeline, "Pipeline "+pipeline.Name+" run "+strconv.Itoa(run.ID)+" stopped")
+
+	w.WriteHeader(http.StatusNoContent)
+}
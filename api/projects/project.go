@@ -90,7 +90,14 @@ func UpdateProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := helpers.Store(r).UpdateProject(body)
+	err := body.Validate()
+
+	if err != nil {
+		helpers.WriteError(w, err)
+		return
+	}
+
+	err = helpers.Store(r).UpdateProject(body)
 
 	if err != nil {
 		helpers.WriteError(w, err)
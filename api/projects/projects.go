@@ -39,7 +39,13 @@ func AddProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	body, err := helpers.Store(r).CreateProject(body)
+	err := body.Validate()
+	if err != nil {
+		helpers.WriteError(w, err)
+		return
+	}
+
+	body, err = helpers.Store(r).CreateProject(body)
 	if err != nil {
 		helpers.WriteError(w, err)
 		return
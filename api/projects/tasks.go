@@ -4,9 +4,12 @@ import (
 	log "github.com/Sirupsen/logrus"
 	"github.com/ansible-semaphore/semaphore/api/helpers"
 	"github.com/ansible-semaphore/semaphore/db"
+	"github.com/ansible-semaphore/semaphore/lib"
 	"github.com/ansible-semaphore/semaphore/util"
 	"github.com/gorilla/context"
+	"io"
 	"net/http"
+	"path"
 	"strconv"
 )
 
@@ -21,8 +24,15 @@ func AddTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	taskObj.ResetServerFields()
+
 	newTask, err := helpers.TaskPool(r).AddTask(taskObj, &user.ID, project.ID)
 
+	if _, ok := err.(*db.ValidationError); ok {
+		helpers.WriteError(w, err)
+		return
+	}
+
 	if err != nil {
 		util.LogErrorWithFields(err, log.Fields{"error": "Cannot write new event to database"})
 		w.WriteHeader(http.StatusInternalServerError)
@@ -121,6 +131,146 @@ func GetTaskOutput(w http.ResponseWriter, r *http.Request) {
 	helpers.WriteJSON(w, http.StatusOK, output)
 }
 
+// GetTaskHosts returns per-host results of the playbook run
+func GetTaskHosts(w http.ResponseWriter, r *http.Request) {
+	task := context.Get(r, "task").(db.Task)
+	project := context.Get(r, "project").(db.Project)
+
+	hosts, err := helpers.Store(r).GetTaskHosts(project.ID, task.ID)
+
+	if err != nil {
+		util.LogErrorWithFields(err, log.Fields{"error": "Bad request. Cannot get task hosts from database"})
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	helpers.WriteJSON(w, http.StatusOK, hosts)
+}
+
+// GetTaskDiffs returns changes reported by the playbook run with --diff
+func GetTaskDiffs(w http.ResponseWriter, r *http.Request) {
+	task := context.Get(r, "task").(db.Task)
+	project := context.Get(r, "project").(db.Project)
+
+	diffs, err := helpers.Store(r).GetTaskDiffs(project.ID, task.ID)
+
+	if err != nil {
+		util.LogErrorWithFields(err, log.Fields{"error": "Bad request. Cannot get task diffs from database"})
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	helpers.WriteJSON(w, http.StatusOK, diffs)
+}
+
+// GetTaskAnnotations returns problems found by validation of the playbook before the run
+func GetTaskAnnotations(w http.ResponseWriter, r *http.Request) {
+	task := context.Get(r, "task").(db.Task)
+	project := context.Get(r, "project").(db.Project)
+
+	annotations, err := helpers.Store(r).GetTaskAnnotations(project.ID, task.ID)
+
+	if err != nil {
+		util.LogErrorWithFields(err, log.Fields{"error": "Bad request. Cannot get task annotations from database"})
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	helpers.WriteJSON(w, http.StatusOK, annotations)
+}
+
+// checkRunsToSearch limits number of the latest successful tasks of the template
+// which are searched for the check run.
+const checkRunsToSearch = 100
+
+// GetTaskCheckRun returns the latest successful dry run with diffs of the same template
+// and parameters as the task, so reviewers can inspect changes of the task before approving it.
+func GetTaskCheckRun(w http.ResponseWriter, r *http.Request) {
+	task := context.Get(r, "task").(db.Task)
+	project := context.Get(r, "project").(db.Project)
+	store := helpers.Store(r)
+
+	if err := db.FillTask(&task); err != nil {
+		helpers.WriteError(w, err)
+		return
+	}
+
+	tasks, err := store.GetTemplateTasksByStatus(project.ID, task.TemplateID, db.TaskSuccessStatus, db.RetrieveQueryParams{
+		Count: checkRunsToSearch,
+	})
+
+	if err != nil {
+		helpers.WriteError(w, err)
+		return
+	}
+
+	for _, t := range tasks {
+		if t.ID == task.ID || !t.DryRun || !t.Diff {
+			continue
+		}
+
+		if task.HasSameParams(t.Task) {
+			helpers.WriteJSON(w, http.StatusOK, t.Task)
+			return
+		}
+	}
+
+	helpers.WriteError(w, db.ErrNotFound)
+}
+
+// GetTaskArtifacts returns files collected from the task.
+func GetTaskArtifacts(w http.ResponseWriter, r *http.Request) {
+	task := context.Get(r, "task").(db.Task)
+	project := context.Get(r, "project").(db.Project)
+
+	artifacts, err := helpers.Store(r).GetTaskArtifacts(project.ID, task.ID)
+
+	if err != nil {
+		util.LogErrorWithFields(err, log.Fields{"error": "Bad request. Cannot get task artifacts from database"})
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	helpers.WriteJSON(w, http.StatusOK, artifacts)
+}
+
+// DownloadTaskArtifact writes content of the artifact to the response.
+func DownloadTaskArtifact(w http.ResponseWriter, r *http.Request) {
+	task := context.Get(r, "task").(db.Task)
+	project := context.Get(r, "project").(db.Project)
+
+	artifactID, err := helpers.GetIntParam("artifact_id", w, r)
+	if err != nil {
+		return
+	}
+
+	artifact, err := helpers.Store(r).GetTaskArtifact(project.ID, task.ID, artifactID)
+
+	if err != nil {
+		helpers.WriteError(w, err)
+		return
+	}
+
+	content, err := lib.GetArtifactStore().Get(task.ID, artifact.Name)
+
+	if err != nil {
+		util.LogErrorWithFields(err, log.Fields{"error": "Cannot read task artifact"})
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	defer content.Close() //nolint: errcheck
+
+	w.Header().Set("content-type", "application/octet-stream")
+	w.Header().Set("content-disposition", "attachment; filename=\""+path.Base(artifact.Name)+"\"")
+	w.Header().Set("content-length", strconv.FormatInt(artifact.Size, 10))
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = io.Copy(w, content); err != nil {
+		log.Error(err)
+	}
+}
+
 func StopTask(w http.ResponseWriter, r *http.Request) {
 	targetTask := context.Get(r, "task").(db.Task)
 	project := context.Get(r, "project").(db.Project)
@@ -139,6 +289,76 @@ func StopTask(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// ApproveTask puts the task awaiting approval to the queue
+func ApproveTask(w http.ResponseWriter, r *http.Request) {
+	decideTaskApproval(w, r, true)
+}
+
+// RejectTask stops the task awaiting approval
+func RejectTask(w http.ResponseWriter, r *http.Request) {
+	decideTaskApproval(w, r, false)
+}
+
+func decideTaskApproval(w http.ResponseWriter, r *http.Request, approve bool) {
+	targetTask := context.Get(r, "task").(db.Task)
+	user := context.Get(r, "user").(*db.User)
+	project := context.Get(r, "project").(db.Project)
+
+	if targetTask.ProjectID != project.ID {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var decision struct {
+		Reason string `json:"reason"`
+	}
+
+	// the reason is optional, so the body can be empty
+	if r.ContentLength != 0 && !helpers.Bind(w, r, &decision) {
+		return
+	}
+
+	tpl, err := helpers.Store(r).GetTemplate(project.ID, targetTask.TemplateID)
+	if err != nil {
+		helpers.WriteError(w, err)
+		return
+	}
+
+	projectUser, err := helpers.Store(r).GetProjectUser(project.ID, user.ID)
+	if err != nil {
+		helpers.WriteError(w, err)
+		return
+	}
+
+	if !tpl.IsApprover(projectUser) {
+		log.Warn(user.Username + " is not permitted to approve tasks of template " + tpl.Name)
+		helpers.WriteJSON(w, http.StatusForbidden, map[string]string{
+			"error": "You are not an approver of the template",
+		})
+		return
+	}
+
+	if approve && targetTask.UserID != nil && *targetTask.UserID == user.ID {
+		helpers.WriteJSON(w, http.StatusForbidden, map[string]string{
+			"error": "Task can not be approved by the user who started it",
+		})
+		return
+	}
+
+	if approve {
+		err = helpers.TaskPool(r).ApproveTask(targetTask, user.ID, decision.Reason)
+	} else {
+		err = helpers.TaskPool(r).RejectTask(targetTask, user.ID, decision.Reason)
+	}
+
+	if err != nil {
+		helpers.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // RemoveTask removes a task from the database
 func RemoveTask(w http.ResponseWriter, r *http.Request) {
 	targetTask := context.Get(r, "task").(db.Task)
@@ -147,8 +367,8 @@ func RemoveTask(w http.ResponseWriter, r *http.Request) {
 
 	activeTask := helpers.TaskPool(r).GetTask(targetTask.ID)
 
-	if activeTask != nil {
-		// can't delete task in queue or running
+	if activeTask != nil || targetTask.Status == db.TaskAwaitingApprovalStatus {
+		// can't delete task in queue, running or awaiting approval
 		// task must be stopped firstly
 		w.WriteHeader(http.StatusBadRequest)
 		return
@@ -167,5 +387,9 @@ func RemoveTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err = lib.GetArtifactStore().Delete(targetTask.ID); err != nil {
+		log.Error(err)
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
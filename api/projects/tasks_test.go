@@ -0,0 +1,110 @@
+package projects
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ansible-semaphore/semaphore/db"
+	"github.com/ansible-semaphore/semaphore/db/bolt"
+	"github.com/ansible-semaphore/semaphore/services/tasks"
+	"github.com/ansible-semaphore/semaphore/util"
+	"github.com/gorilla/context"
+)
+
+func TestAddTaskIgnoresClientRetry(t *testing.T) {
+	oldConfig := util.Config
+	util.Config = &util.ConfigType{}
+	defer func() { util.Config = oldConfig }()
+
+	store := bolt.CreateTestStore()
+
+	proj, err := store.CreateProject(db.Project{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := store.CreateUserWithoutPassword(db.User{Name: "Test", Username: "test", Email: "test@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := store.CreateAccessKey(db.AccessKey{ProjectID: &proj.ID, Type: db.AccessKeyNone})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := store.CreateRepository(db.Repository{
+		ProjectID: proj.ID,
+		SSHKeyID:  key.ID,
+		Name:      "Test",
+		GitURL:    "git@example.com:test/test",
+		GitBranch: "master",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inv, err := store.CreateInventory(db.Inventory{ProjectID: proj.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tpl, err := store.CreateTemplate(db.Template{
+		Name:            "Deploy",
+		Playbook:        "deploy.yml",
+		ProjectID:       proj.ID,
+		RepositoryID:    repo.ID,
+		InventoryID:     inv.ID,
+		Type:            db.TemplateDeploy,
+		RequireApproval: true,
+		Approvers:       db.TemplateApprovers{UserIDs: []int{user.ID}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := store.CreateTask(db.Task{TemplateID: tpl.ID, ProjectID: proj.ID, Status: db.TaskSuccessStatus})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"template_id":      tpl.ID,
+		"retry_of_task_id": first.ID,
+		"attempt":          2,
+		"pipeline_stage":   "deploy",
+	})
+
+	pool := tasks.CreateTaskPool(&store)
+
+	req, _ := http.NewRequest("POST", "/api/project/1/tasks", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	context.Set(req, "project", proj)
+	context.Set(req, "user", &user)
+	context.Set(req, "store", &store)
+	context.Set(req, "task_pool", &pool)
+	defer context.Clear(req)
+
+	rr := httptest.NewRecorder()
+	AddTask(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	var task db.Task
+	if err = json.Unmarshal(rr.Body.Bytes(), &task); err != nil {
+		t.Fatal(err)
+	}
+
+	if task.Status != db.TaskAwaitingApprovalStatus {
+		t.Fatalf("expected task to await approval, got %q", task.Status)
+	}
+
+	if task.RetryOfTaskID != nil || task.Attempt != 1 || task.PipelineStage != "" {
+		t.Fatal("server fields must not be taken from the request")
+	}
+}
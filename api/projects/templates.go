@@ -176,3 +176,78 @@ func RemoveTemplate(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// GetTemplateBuilds returns successful tasks of the build template of the deploy template,
+// which can be redeployed
+func GetTemplateBuilds(w http.ResponseWriter, r *http.Request) {
+	tpl := context.Get(r, "template").(db.Template)
+
+	if tpl.Type != db.TemplateDeploy || tpl.BuildTemplateID == nil {
+		helpers.WriteJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "Only deploy templates with a build template can be redeployed",
+		})
+		return
+	}
+
+	builds, err := helpers.Store(r).GetTemplateBuilds(tpl.ProjectID, *tpl.BuildTemplateID, helpers.QueryParams(r.URL))
+	if err != nil {
+		helpers.WriteError(w, err)
+		return
+	}
+
+	helpers.WriteJSON(w, http.StatusOK, builds)
+}
+
+// RedeployTemplate creates a task of the deploy template which deploys a previous build again
+func RedeployTemplate(w http.ResponseWriter, r *http.Request) {
+	tpl := context.Get(r, "template").(db.Template)
+	user := context.Get(r, "user").(*db.User)
+
+	var taskObj db.Task
+
+	if !helpers.Bind(w, r, &taskObj) {
+		return
+	}
+
+	taskObj.ResetServerFields()
+
+	newTask, err := helpers.TaskPool(r).AddRollbackTask(taskObj, tpl, &user.ID)
+	if err != nil {
+		helpers.WriteError(w, err)
+		return
+	}
+
+	helpers.WriteJSON(w, http.StatusCreated, newTask)
+}
+
+// ValidateTemplate creates a task which checks syntax of the playbook of the template
+// and runs its lint command without running the playbook, e.g. to verify a commit in CI.
+func ValidateTemplate(w http.ResponseWriter, r *http.Request) {
+	tpl := context.Get(r, "template").(db.Template)
+	user := context.Get(r, "user").(*db.User)
+
+	var taskObj db.Task
+
+	if r.ContentLength > 0 && !helpers.Bind(w, r, &taskObj) {
+		return
+	}
+
+	newTask, err := helpers.TaskPool(r).AddTask(db.Task{
+		TemplateID:   tpl.ID,
+		ProjectID:    tpl.ProjectID,
+		ValidateOnly: true,
+		Playbook:     taskObj.Playbook,
+		Environment:  taskObj.Environment,
+		Arguments:    taskObj.Arguments,
+		CommitHash:   taskObj.CommitHash,
+		SurveyValues: taskObj.SurveyValues,
+		Message:      taskObj.Message,
+	}, &user.ID, tpl.ProjectID)
+
+	if err != nil {
+		helpers.WriteError(w, err)
+		return
+	}
+
+	helpers.WriteJSON(w, http.StatusCreated, newTask)
+}
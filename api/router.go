@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/ansible-semaphore/semaphore/api/projects"
+	"github.com/ansible-semaphore/semaphore/api/runners"
 	"github.com/ansible-semaphore/semaphore/api/sockets"
 	"github.com/ansible-semaphore/semaphore/util"
 	"github.com/gobuffalo/packr"
@@ -71,6 +72,16 @@ func Route() *mux.Router {
 	publicAPIRouter.HandleFunc("/auth/login", login).Methods("POST")
 	publicAPIRouter.HandleFunc("/auth/logout", logout).Methods("POST")
 
+	internalAPI := publicAPIRouter.PathPrefix("/internal").Subrouter()
+	internalAPI.HandleFunc("/runners", runners.RegisterRunner).Methods("POST")
+
+	runnersAPI := internalAPI.PathPrefix("/runners").Subrouter()
+	runnersAPI.Use(runners.RunnerMiddleware)
+	runnersAPI.Path("/{runner_id}").HandlerFunc(runners.GetRunner).Methods("GET", "HEAD")
+	runnersAPI.Path("/{runner_id}").HandlerFunc(runners.UpdateRunner).Methods("PUT")
+	runnersAPI.Path("/{runner_id}/tasks/{task_id}/artifacts").HandlerFunc(runners.AddTaskArtifact).Methods("POST")
+	runnersAPI.Path("/{runner_id}/tasks/{task_id}/artifacts/{artifact_id}").HandlerFunc(runners.GetBuildArtifact).Methods("GET")
+
 	authenticatedAPI := r.PathPrefix(webPath + "api").Subrouter()
 	authenticatedAPI.Use(JSONMiddleware, authentication)
 
@@ -140,6 +151,9 @@ func Route() *mux.Router {
 	projectUserAPI.Path("/views").HandlerFunc(projects.AddView).Methods("POST")
 	projectUserAPI.Path("/views/positions").HandlerFunc(projects.SetViewPositions).Methods("POST")
 
+	projectUserAPI.Path("/pipelines").HandlerFunc(projects.GetPipelines).Methods("GET", "HEAD")
+	projectUserAPI.Path("/pipelines").HandlerFunc(projects.AddPipeline).Methods("POST")
+
 	projectAdminAPI := authenticatedAPI.Path("/project/{project_id}").Subrouter()
 	projectAdminAPI.Use(projects.ProjectMiddleware, projects.MustBeAdmin)
 	projectAdminAPI.Methods("PUT").HandlerFunc(projects.UpdateProject)
@@ -199,14 +213,25 @@ func Route() *mux.Router {
 	projectTmplManagement.HandleFunc("/{template_id}/tasks", projects.GetAllTasks).Methods("GET")
 	projectTmplManagement.HandleFunc("/{template_id}/tasks/last", projects.GetLastTasks).Methods("GET")
 	projectTmplManagement.HandleFunc("/{template_id}/schedules", projects.GetTemplateSchedules).Methods("GET")
+	projectTmplManagement.HandleFunc("/{template_id}/redeploy", projects.GetTemplateBuilds).Methods("GET", "HEAD")
+	projectTmplManagement.HandleFunc("/{template_id}/redeploy", projects.RedeployTemplate).Methods("POST")
+	projectTmplManagement.HandleFunc("/{template_id}/validate", projects.ValidateTemplate).Methods("POST")
 
 	projectTaskManagement := projectUserAPI.PathPrefix("/tasks").Subrouter()
 	projectTaskManagement.Use(projects.GetTaskMiddleware)
 
 	projectTaskManagement.HandleFunc("/{task_id}/output", projects.GetTaskOutput).Methods("GET", "HEAD")
+	projectTaskManagement.HandleFunc("/{task_id}/hosts", projects.GetTaskHosts).Methods("GET", "HEAD")
+	projectTaskManagement.HandleFunc("/{task_id}/diffs", projects.GetTaskDiffs).Methods("GET", "HEAD")
+	projectTaskManagement.HandleFunc("/{task_id}/annotations", projects.GetTaskAnnotations).Methods("GET", "HEAD")
+	projectTaskManagement.HandleFunc("/{task_id}/check_run", projects.GetTaskCheckRun).Methods("GET", "HEAD")
+	projectTaskManagement.HandleFunc("/{task_id}/artifacts", projects.GetTaskArtifacts).Methods("GET", "HEAD")
+	projectTaskManagement.HandleFunc("/{task_id}/artifacts/{artifact_id}", projects.DownloadTaskArtifact).Methods("GET", "HEAD")
 	projectTaskManagement.HandleFunc("/{task_id}", projects.GetTask).Methods("GET", "HEAD")
 	projectTaskManagement.HandleFunc("/{task_id}", projects.RemoveTask).Methods("DELETE")
 	projectTaskManagement.HandleFunc("/{task_id}/stop", projects.StopTask).Methods("POST")
+	projectTaskManagement.HandleFunc("/{task_id}/approve", projects.ApproveTask).Methods("POST")
+	projectTaskManagement.HandleFunc("/{task_id}/reject", projects.RejectTask).Methods("POST")
 
 	projectScheduleManagement := projectUserAPI.PathPrefix("/schedules").Subrouter()
 	projectScheduleManagement.Use(projects.SchedulesMiddleware)
@@ -221,6 +246,19 @@ func Route() *mux.Router {
 	projectViewManagement.HandleFunc("/{view_id}", projects.RemoveView).Methods("DELETE")
 	projectViewManagement.HandleFunc("/{view_id}/templates", projects.GetViewTemplates).Methods("GET", "HEAD")
 
+	projectPipelineRunManagement := projectUserAPI.PathPrefix("/pipelines/{pipeline_id}/runs").Subrouter()
+	projectPipelineRunManagement.Use(projects.PipelineMiddleware, projects.PipelineRunMiddleware)
+	projectPipelineRunManagement.HandleFunc("/{run_id}", projects.GetPipelineRun).Methods("GET", "HEAD")
+	projectPipelineRunManagement.HandleFunc("/{run_id}/stop", projects.StopPipelineRun).Methods("POST")
+
+	projectPipelineManagement := projectUserAPI.PathPrefix("/pipelines").Subrouter()
+	projectPipelineManagement.Use(projects.PipelineMiddleware)
+	projectPipelineManagement.HandleFunc("/{pipeline_id}", projects.GetPipelines).Methods("GET", "HEAD")
+	projectPipelineManagement.HandleFunc("/{pipeline_id}", projects.UpdatePipeline).Methods("PUT")
+	projectPipelineManagement.HandleFunc("/{pipeline_id}", projects.RemovePipeline).Methods("DELETE")
+	projectPipelineManagement.HandleFunc("/{pipeline_id}/runs", projects.GetPipelineRuns).Methods("GET", "HEAD")
+	projectPipelineManagement.HandleFunc("/{pipeline_id}/runs", projects.RunPipeline).Methods("POST")
+
 	if os.Getenv("DEBUG") == "1" {
 		defer debugPrintRoutes(r)
 	}
@@ -0,0 +1,185 @@
+package runners
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/ansible-semaphore/semaphore/api/helpers"
+	"github.com/ansible-semaphore/semaphore/db"
+	"github.com/ansible-semaphore/semaphore/services/runners"
+	"github.com/ansible-semaphore/semaphore/services/tasks"
+	"github.com/ansible-semaphore/semaphore/util"
+	"github.com/gorilla/context"
+)
+
+// longPollingTimeout is the maximum time the server holds runner's request
+// waiting for a new task.
+const longPollingTimeout = 25 * time.Second
+
+// tokensEqual compares tokens in constant time, so the comparison does not reveal
+// how many leading characters of the token are guessed.
+func tokensEqual(a string, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// RunnerMiddleware ensures the runner exists and the request contains its token.
+func RunnerMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		runnerID, err := helpers.GetIntParam("runner_id", w, r)
+		if err != nil {
+			return
+		}
+
+		runner, err := helpers.Store(r).GetRunner(runnerID)
+
+		if err != nil {
+			if err != db.ErrNotFound {
+				log.Error(err)
+			}
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if runner.Token == "" || !tokensEqual(r.Header.Get("X-Runner-Token"), runner.Token) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		context.Set(r, "runner", runner)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RegisterRunner creates a new runner if the request contains a valid registration token.
+func RegisterRunner(w http.ResponseWriter, r *http.Request) {
+	var registration runners.RunnerRegistration
+
+	if !helpers.Bind(w, r, &registration) {
+		return
+	}
+
+	if util.Config.RunnerRegistrationToken == "" ||
+		!tokensEqual(registration.RegistrationToken, util.Config.RunnerRegistrationToken) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	token := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, token); err != nil {
+		log.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	runner, err := helpers.Store(r).CreateRunner(db.Runner{
+		Token: base64.URLEncoding.EncodeToString(token),
+	})
+
+	if err != nil {
+		helpers.WriteError(w, err)
+		return
+	}
+
+	helpers.WriteJSON(w, http.StatusCreated, runners.RunnerConfig{
+		RunnerID: runner.ID,
+		Token:    runner.Token,
+	})
+}
+
+// GetRunner waits for tasks which should be run by the runner.
+func GetRunner(w http.ResponseWriter, r *http.Request) {
+	runner := context.Get(r, "runner").(db.Runner)
+
+	pool := helpers.TaskPool(r)
+	state := pool.GetRunnerState(runner.ID, longPollingTimeout, r.Context().Done())
+
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err := json.NewEncoder(w).Encode(state)
+	if f, ok := w.(http.Flusher); ok && err == nil {
+		f.Flush()
+	}
+
+	// the runner disconnected and did not receive the jobs
+	if err != nil || r.Context().Err() != nil {
+		pool.ReturnRunnerState(runner.ID, state)
+	}
+}
+
+// UpdateRunner receives output and state of tasks which the runner runs.
+func UpdateRunner(w http.ResponseWriter, r *http.Request) {
+	runner := context.Get(r, "runner").(db.Runner)
+
+	var progress tasks.RunnerProgress
+
+	if !helpers.Bind(w, r, &progress) {
+		return
+	}
+
+	res := helpers.TaskPool(r).SetRunnerProgress(runner.ID, progress)
+
+	helpers.WriteJSON(w, http.StatusOK, res)
+}
+
+// AddTaskArtifact receives a file produced by a task which the runner runs.
+func AddTaskArtifact(w http.ResponseWriter, r *http.Request) {
+	runner := context.Get(r, "runner").(db.Runner)
+
+	taskID, err := helpers.GetIntParam("task_id", w, r)
+	if err != nil {
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err = helpers.TaskPool(r).AddRunnerArtifact(runner.ID, taskID, name, r.Body)
+
+	if err != nil {
+		helpers.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetBuildArtifact sends an artifact of the build task of a deploy task which the runner runs.
+func GetBuildArtifact(w http.ResponseWriter, r *http.Request) {
+	runner := context.Get(r, "runner").(db.Runner)
+
+	taskID, err := helpers.GetIntParam("task_id", w, r)
+	if err != nil {
+		return
+	}
+
+	artifactID, err := helpers.GetIntParam("artifact_id", w, r)
+	if err != nil {
+		return
+	}
+
+	content, err := helpers.TaskPool(r).GetRunnerBuildArtifact(runner.ID, taskID, artifactID)
+
+	if err != nil {
+		helpers.WriteError(w, err)
+		return
+	}
+
+	defer content.Close() //nolint: errcheck
+
+	w.Header().Set("content-type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = io.Copy(w, content); err != nil {
+		log.Error(err)
+	}
+}
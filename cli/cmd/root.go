@@ -7,6 +7,7 @@ import (
 	"github.com/ansible-semaphore/semaphore/api/sockets"
 	"github.com/ansible-semaphore/semaphore/db"
 	"github.com/ansible-semaphore/semaphore/db/factory"
+	"github.com/ansible-semaphore/semaphore/services/retention"
 	"github.com/ansible-semaphore/semaphore/services/schedules"
 	"github.com/ansible-semaphore/semaphore/services/tasks"
 	"github.com/ansible-semaphore/semaphore/util"
@@ -16,6 +17,8 @@ import (
 	"go.etcd.io/bbolt"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 )
 
 var configPath string
@@ -44,9 +47,11 @@ func runService() {
 	store := createStore()
 	taskPool := tasks.CreateTaskPool(store)
 	schedulePool := schedules.CreateSchedulePool(store, &taskPool)
+	cleaner := retention.CreateCleaner(store)
 
 	defer store.Close()
 	defer schedulePool.Destroy()
+	defer taskPool.Destroy()
 
 	dialect, err := util.Config.GetDialect()
 	if err != nil {
@@ -70,6 +75,7 @@ func runService() {
 	go sockets.StartWS()
 	go schedulePool.Run()
 	go taskPool.Run()
+	go cleaner.Run()
 
 	route := api.Route()
 
@@ -89,9 +95,23 @@ func runService() {
 
 	fmt.Println("Server is running")
 
-	err = http.ListenAndServe(util.Config.Interface+util.Config.Port, cropTrailingSlashMiddleware(router))
+	server := &http.Server{
+		Addr:    util.Config.Interface + util.Config.Port,
+		Handler: cropTrailingSlashMiddleware(router),
+	}
 
-	if err != nil {
+	// stop the server on termination, so pools write their state before exit
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+		<-quit
+		fmt.Println("Server is stopping")
+		_ = server.Close()
+	}()
+
+	err = server.ListenAndServe()
+
+	if err != nil && err != http.ErrServerClosed {
 		log.Panic(err)
 	}
 }
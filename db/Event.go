@@ -32,6 +32,7 @@ const (
 	EventTemplate    EventObjectType = "template"
 	EventUser        EventObjectType = "user"
 	EventView        EventObjectType = "view"
+	EventPipeline    EventObjectType = "pipeline"
 )
 
 func FillEvents(d Store, events []Event) (err error) {
@@ -58,6 +58,24 @@ func GetMigrations() []Migration {
 		{Version: "2.8.42"},
 		{Version: "2.8.51"},
 		{Version: "2.8.57"},
+		{Version: "2.8.58"},
+		{Version: "2.8.59"},
+		{Version: "2.8.60"},
+		{Version: "2.8.61"},
+		{Version: "2.8.62"},
+		{Version: "2.8.63"},
+		{Version: "2.8.64"},
+		{Version: "2.8.65"},
+		{Version: "2.8.66"},
+		{Version: "2.8.67"},
+		{Version: "2.8.68"},
+		{Version: "2.8.69"},
+		{Version: "2.8.70"},
+		{Version: "2.8.71"},
+		{Version: "2.8.72"},
+		{Version: "2.8.73"},
+		{Version: "2.8.74"},
+		{Version: "2.8.75"},
 	}
 }
 
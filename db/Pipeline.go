@@ -0,0 +1,161 @@
+package db
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// PipelineStageCondition defines which results of the needed stages start the stage.
+type PipelineStageCondition string
+
+const (
+	PipelineStageOnSuccess PipelineStageCondition = ""
+	PipelineStageOnFailure PipelineStageCondition = "failure"
+	PipelineStageAlways    PipelineStageCondition = "always"
+)
+
+// PipelineStage runs a task of the template when the stages it needs are finished.
+type PipelineStage struct {
+	Name       string `json:"name"`
+	TemplateID int    `json:"template_id"`
+	// Needs are names of stages which must finish before the stage starts.
+	// Stages without needs start with the pipeline.
+	Needs     []string               `json:"needs"`
+	Condition PipelineStageCondition `json:"condition"`
+}
+
+// Pipeline is a directed acyclic graph of templates of the project.
+type Pipeline struct {
+	ID          int     `db:"id" json:"id"`
+	ProjectID   int     `db:"project_id" json:"project_id"`
+	Name        string  `db:"name" json:"name"`
+	Description *string `db:"description" json:"description"`
+
+	// StagesJSON used internally for read from database.
+	// Do not use it in your code. Use Stages instead.
+	StagesJSON *string         `db:"stages" json:"-"`
+	Stages     []PipelineStage `db:"-" json:"stages"`
+}
+
+// PipelineRun is a single execution of a pipeline.
+type PipelineRun struct {
+	ID         int        `db:"id" json:"id"`
+	ProjectID  int        `db:"project_id" json:"project_id"`
+	PipelineID int        `db:"pipeline_id" json:"pipeline_id"`
+	UserID     *int       `db:"user_id" json:"user_id"`
+	Status     TaskStatus `db:"status" json:"status"`
+	// Environment is JSON of extra variables which are passed to tasks of all stages.
+	Environment string     `db:"environment" json:"environment"`
+	Created     time.Time  `db:"created" json:"created"`
+	End         *time.Time `db:"end" json:"end"`
+}
+
+// PipelineRunWithTasks is a run with tasks of its stages.
+type PipelineRunWithTasks struct {
+	PipelineRun
+	Tasks []Task `json:"tasks"`
+}
+
+func (p *Pipeline) GetStage(name string) *PipelineStage {
+	for i := range p.Stages {
+		if p.Stages[i].Name == name {
+			return &p.Stages[i]
+		}
+	}
+	return nil
+}
+
+func (p *Pipeline) Validate() error {
+	if p.Name == "" {
+		return &ValidationError{"pipeline name can not be empty"}
+	}
+
+	if len(p.Stages) == 0 {
+		return &ValidationError{"pipeline must have stages"}
+	}
+
+	stages := make(map[string]PipelineStage)
+
+	for _, stage := range p.Stages {
+		if stage.Name == "" {
+			return &ValidationError{"pipeline stage name can not be empty"}
+		}
+
+		if _, ok := stages[stage.Name]; ok {
+			return &ValidationError{"pipeline stage " + stage.Name + " is defined twice"}
+		}
+
+		if stage.TemplateID == 0 {
+			return &ValidationError{"pipeline stage " + stage.Name + " must have template"}
+		}
+
+		switch stage.Condition {
+		case PipelineStageOnSuccess, PipelineStageAlways:
+		case PipelineStageOnFailure:
+			if len(stage.Needs) == 0 {
+				return &ValidationError{"pipeline stage " + stage.Name + " runs on failure but needs no stages"}
+			}
+		default:
+			return &ValidationError{"unknown condition of pipeline stage " + stage.Name}
+		}
+
+		stages[stage.Name] = stage
+	}
+
+	for _, stage := range p.Stages {
+		for _, need := range stage.Needs {
+			if _, ok := stages[need]; !ok {
+				return &ValidationError{"pipeline stage " + stage.Name + " needs unknown stage " + need}
+			}
+		}
+	}
+
+	// stages are removed in topological order, stages left in a cycle are never removed
+	done := make(map[string]bool)
+
+	for len(done) < len(stages) {
+		progress := false
+
+		for _, stage := range p.Stages {
+			if done[stage.Name] {
+				continue
+			}
+
+			ready := true
+			for _, need := range stage.Needs {
+				if !done[need] {
+					ready = false
+					break
+				}
+			}
+
+			if ready {
+				done[stage.Name] = true
+				progress = true
+			}
+		}
+
+		if !progress {
+			return &ValidationError{"pipeline stages can not depend on each other cyclically"}
+		}
+	}
+
+	return nil
+}
+
+func FillPipeline(pipeline *Pipeline) error {
+	if pipeline.StagesJSON == nil {
+		return nil
+	}
+	return json.Unmarshal([]byte(*pipeline.StagesJSON), &pipeline.Stages)
+}
+
+func FillPipelines(pipelines []Pipeline) (err error) {
+	for i := range pipelines {
+		err = FillPipeline(&pipelines[i])
+		if err != nil {
+			return
+		}
+	}
+	return
+}
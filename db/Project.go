@@ -1,6 +1,8 @@
 package db
 
 import (
+	"encoding/json"
+	"regexp"
 	"time"
 )
 
@@ -12,4 +14,46 @@ type Project struct {
 	Alert            bool      `db:"alert" json:"alert"`
 	AlertChat        *string   `db:"alert_chat" json:"alert_chat"`
 	MaxParallelTasks int       `db:"max_parallel_tasks" json:"max_parallel_tasks"`
+
+	// MaxTasksPerTemplate overrides number of the latest tasks of every template
+	// which are kept by the retention policy. Zero means the global setting
+	// is used, negative value means tasks are never deleted.
+	MaxTasksPerTemplate int `db:"max_tasks_per_template" json:"max_tasks_per_template"`
+	// OutputRetentionDays overrides number of days the output of finished tasks is kept.
+	// Zero means the global setting is used, negative value means output is kept forever.
+	OutputRetentionDays int `db:"output_retention_days" json:"output_retention_days"`
+
+	// LogMaskPatternsJSON used internally for read from database.
+	// Do not use it in your code. Use LogMaskPatterns instead.
+	LogMaskPatternsJSON *string `db:"log_mask_patterns" json:"-"`
+	// LogMaskPatterns are regular expressions of additional secrets
+	// which are replaced with **** in task output.
+	LogMaskPatterns []string `db:"-" json:"log_mask_patterns"`
+}
+
+func (project *Project) Validate() error {
+	for _, pattern := range project.LogMaskPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return &ValidationError{"Invalid log mask pattern " + pattern + ": " + err.Error()}
+		}
+	}
+
+	return nil
+}
+
+func FillProject(project *Project) error {
+	if project.LogMaskPatternsJSON == nil {
+		return nil
+	}
+	return json.Unmarshal([]byte(*project.LogMaskPatternsJSON), &project.LogMaskPatterns)
+}
+
+func FillProjects(projects []Project) (err error) {
+	for i := range projects {
+		err = FillProject(&projects[i])
+		if err != nil {
+			return
+		}
+	}
+	return
 }
@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 type RepositoryType string
@@ -31,7 +32,21 @@ type Repository struct {
 	SSHKey AccessKey `db:"-" json:"-"`
 }
 
+// mirrorLocks serializes changes of the same mirror.
+var mirrorLocks sync.Map
+
+// LockMirror locks the mirror of the repository and returns the function which unlocks it.
+func (r Repository) LockMirror() func() {
+	l, _ := mirrorLocks.LoadOrStore(r.GetMirrorPath(), &sync.Mutex{})
+	mutex := l.(*sync.Mutex)
+	mutex.Lock()
+	return mutex.Unlock
+}
+
 func (r Repository) ClearCache() error {
+	unlock := r.LockMirror()
+	defer unlock()
+
 	dir, err := os.Open(util.Config.TmpPath)
 	if err != nil {
 		return err
@@ -42,10 +57,26 @@ func (r Repository) ClearCache() error {
 		return err
 	}
 
+	hasWorkspaces := false
+	for _, f := range files {
+		if f.IsDir() && strings.HasPrefix(f.Name(), r.getWorkspaceDirNamePrefix()) {
+			hasWorkspaces = true
+			break
+		}
+	}
+
 	for _, f := range files {
 		if !f.IsDir() {
 			continue
 		}
+		// workspaces can be used by running tasks, they are removed when tasks finish
+		if strings.HasPrefix(f.Name(), r.getWorkspaceDirNamePrefix()) {
+			continue
+		}
+		// workspaces are git worktrees of the mirror
+		if hasWorkspaces && path.Join(util.Config.TmpPath, f.Name()) == r.GetMirrorPath() {
+			continue
+		}
 		if strings.HasPrefix(f.Name(), r.getDirNamePrefix()) {
 			err = os.RemoveAll(path.Join(util.Config.TmpPath, f.Name()))
 			if err != nil {
@@ -65,11 +96,21 @@ func (r Repository) GetDirName(templateID int) string {
 	return r.getDirNamePrefix() + strconv.Itoa(templateID)
 }
 
-func (r Repository) GetFullPath(templateID int) string {
+// GetMirrorPath returns the location of the bare mirror shared by all tasks.
+func (r Repository) GetMirrorPath() string {
+	return path.Join(util.Config.TmpPath, r.getDirNamePrefix()+"mirror")
+}
+
+// GetWorkspacePath returns the location of the working copy of the task.
+func (r Repository) GetWorkspacePath(taskID int) string {
 	if r.GetType() == RepositoryLocal {
 		return r.GetGitURL()
 	}
-	return path.Join(util.Config.TmpPath, r.GetDirName(templateID))
+	return path.Join(util.Config.TmpPath, r.getWorkspaceDirNamePrefix()+strconv.Itoa(taskID))
+}
+
+func (r Repository) getWorkspaceDirNamePrefix() string {
+	return r.getDirNamePrefix() + "task_"
 }
 
 func (r Repository) GetGitURL() string {
@@ -25,7 +25,16 @@ func TestRepository_ClearCache(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	workspaceDir := path.Join(util.Config.TmpPath, "repository_123_task_7")
+	err = os.MkdirAll(workspaceDir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
 	repo := Repository{ID: 123}
+	err = os.MkdirAll(repo.GetMirrorPath(), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
 	err = repo.ClearCache()
 	if err != nil {
 		t.Fatal(err)
@@ -37,4 +46,24 @@ func TestRepository_ClearCache(t *testing.T) {
 	if !os.IsNotExist(err) {
 		t.Fatal(err)
 	}
+	_, err = os.Stat(workspaceDir)
+	if err != nil {
+		t.Fatal("task workspace must not be deleted")
+	}
+	_, err = os.Stat(repo.GetMirrorPath())
+	if err != nil {
+		t.Fatal("mirror used by task workspaces must not be deleted")
+	}
+	err = os.RemoveAll(workspaceDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.ClearCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = os.Stat(repo.GetMirrorPath())
+	if !os.IsNotExist(err) {
+		t.Fatal("mirror must be deleted if no task uses it")
+	}
 }
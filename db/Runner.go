@@ -0,0 +1,8 @@
+package db
+
+// Runner is a remote task runner registered on the server.
+// Runners pull queued tasks over HTTP and run them on their own hosts.
+type Runner struct {
+	ID    int    `db:"id" json:"id"`
+	Token string `db:"token" json:"-"`
+}
@@ -135,6 +135,8 @@ type Store interface {
 
 	GetProject(projectID int) (Project, error)
 	GetProjects(userID int) ([]Project, error)
+	// GetAllProjects returns projects regardless of their users.
+	GetAllProjects() ([]Project, error)
 	CreateProject(project Project) (Project, error)
 	DeleteProject(projectID int) error
 	UpdateProject(project Project) error
@@ -178,11 +180,35 @@ type Store interface {
 	UpdateTask(task Task) error
 
 	GetTemplateTasks(projectID int, templateID int, params RetrieveQueryParams) ([]TaskWithTpl, error)
+	// GetTemplateTasksByStatus returns tasks of the template which have the status, newest first.
+	GetTemplateTasksByStatus(projectID int, templateID int, status TaskStatus, params RetrieveQueryParams) ([]TaskWithTpl, error)
+	// GetTemplateBuilds returns successful tasks of the template except validations, newest first.
+	GetTemplateBuilds(projectID int, templateID int, params RetrieveQueryParams) ([]TaskWithTpl, error)
 	GetProjectTasks(projectID int, params RetrieveQueryParams) ([]TaskWithTpl, error)
 	GetTask(projectID int, taskID int) (Task, error)
+	// GetTasksByStatus returns tasks of all projects which have one of the statuses,
+	// ordered by creation time.
+	GetTasksByStatus(statuses []TaskStatus) ([]Task, error)
 	DeleteTaskWithOutputs(projectID int, taskID int) error
 	GetTaskOutputs(projectID int, taskID int) ([]TaskOutput, error)
 	CreateTaskOutput(output TaskOutput) (TaskOutput, error)
+	// CreateTaskOutputs stores many output records at once.
+	CreateTaskOutputs(outputs []TaskOutput) error
+	CreateTaskHosts(taskID int, hosts []TaskHost) error
+	GetTaskHosts(projectID int, taskID int) ([]TaskHost, error)
+	CreateTaskDiffs(taskID int, diffs []TaskDiff) error
+	GetTaskDiffs(projectID int, taskID int) ([]TaskDiff, error)
+	CreateTaskAnnotations(taskID int, annotations []TaskAnnotation) error
+	GetTaskAnnotations(projectID int, taskID int) ([]TaskAnnotation, error)
+	CreateTaskArtifact(artifact TaskArtifact) (TaskArtifact, error)
+	GetTaskArtifacts(projectID int, taskID int) ([]TaskArtifact, error)
+	GetTaskArtifact(projectID int, taskID int, artifactID int) (TaskArtifact, error)
+	// DeleteTaskOutputsBefore removes output of tasks of the project which
+	// finished before the time. The tasks themselves are kept.
+	DeleteTaskOutputsBefore(projectID int, before time.Time) error
+	// CompressTaskOutputsBefore replaces output records of tasks of the project which
+	// finished before the time with a single compressed TaskOutputArchive per task.
+	CompressTaskOutputsBefore(projectID int, before time.Time) error
 
 	GetView(projectID int, viewID int) (View, error)
 	GetViews(projectID int) ([]View, error)
@@ -190,6 +216,27 @@ type Store interface {
 	CreateView(view View) (View, error)
 	DeleteView(projectID int, viewID int) error
 	SetViewPositions(projectID int, viewPositions map[int]int) error
+
+	GetPipelines(projectID int) ([]Pipeline, error)
+	GetPipeline(projectID int, pipelineID int) (Pipeline, error)
+	CreatePipeline(pipeline Pipeline) (Pipeline, error)
+	UpdatePipeline(pipeline Pipeline) error
+	// DeletePipeline removes the pipeline with its runs. Tasks of the runs are kept.
+	DeletePipeline(projectID int, pipelineID int) error
+
+	CreatePipelineRun(run PipelineRun) (PipelineRun, error)
+	// UpdatePipelineRun updates status and end time of the run.
+	UpdatePipelineRun(run PipelineRun) error
+	GetPipelineRun(projectID int, runID int) (PipelineRun, error)
+	// GetPipelineRuns returns runs of the pipeline, newest first.
+	GetPipelineRuns(projectID int, pipelineID int, params RetrieveQueryParams) ([]PipelineRun, error)
+	// GetPipelineRunTasks returns tasks started by the run ordered by creation time.
+	GetPipelineRunTasks(projectID int, runID int) ([]Task, error)
+
+	GetRunner(runnerID int) (Runner, error)
+	GetRunners() ([]Runner, error)
+	CreateRunner(runner Runner) (Runner, error)
+	DeleteRunner(runnerID int) error
 }
 
 var AccessKeyProps = ObjectProps{
@@ -288,6 +335,34 @@ var TaskOutputProps = ObjectProps{
 	Type:      reflect.TypeOf(TaskOutput{}),
 }
 
+var TaskHostProps = ObjectProps{
+	TableName: "task__host",
+	Type:      reflect.TypeOf(TaskHost{}),
+}
+
+var TaskDiffProps = ObjectProps{
+	TableName:         "task__diff",
+	Type:              reflect.TypeOf(TaskDiff{}),
+	PrimaryColumnName: "id",
+}
+
+var TaskAnnotationProps = ObjectProps{
+	TableName:         "task__annotation",
+	Type:              reflect.TypeOf(TaskAnnotation{}),
+	PrimaryColumnName: "id",
+}
+
+var TaskArtifactProps = ObjectProps{
+	TableName:         "task__artifact",
+	Type:              reflect.TypeOf(TaskArtifact{}),
+	PrimaryColumnName: "id",
+}
+
+var TaskOutputArchiveProps = ObjectProps{
+	TableName: "task__output_archive",
+	Type:      reflect.TypeOf(TaskOutputArchive{}),
+}
+
 var ViewProps = ObjectProps{
 	TableName:            "project__view",
 	Type:                 reflect.TypeOf(View{}),
@@ -295,6 +370,30 @@ var ViewProps = ObjectProps{
 	DefaultSortingColumn: "position",
 }
 
+var PipelineProps = ObjectProps{
+	TableName:             "project__pipeline",
+	Type:                  reflect.TypeOf(Pipeline{}),
+	PrimaryColumnName:     "id",
+	ReferringColumnSuffix: "pipeline_id",
+	SortableColumns:       []string{"name"},
+	DefaultSortingColumn:  "name",
+}
+
+var PipelineRunProps = ObjectProps{
+	TableName:             "project__pipeline_run",
+	Type:                  reflect.TypeOf(PipelineRun{}),
+	PrimaryColumnName:     "id",
+	ReferringColumnSuffix: "pipeline_run_id",
+	SortInverted:          true,
+}
+
+var RunnerProps = ObjectProps{
+	TableName:         "runner",
+	Type:              reflect.TypeOf(Runner{}),
+	PrimaryColumnName: "id",
+	IsGlobal:          true,
+}
+
 func (p ObjectProps) GetReferringFieldsFrom(t reflect.Type) (fields []string, err error) {
 	n := t.NumField()
 	for i := 0; i < n; i++ {
@@ -0,0 +1,183 @@
+package db
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func (v *SurveyVar) Validate() error {
+	if v.Name == "" {
+		return &ValidationError{"survey variable name can not be empty"}
+	}
+
+	switch v.Type {
+	case SurveyVarStr, SurveyVarInt, SurveyVarBool:
+	case SurveyVarSecret:
+		if v.DefaultValue != "" {
+			return &ValidationError{"secret survey variable " + v.Name + " can not have default value"}
+		}
+	case SurveyVarEnum, SurveyVarMultiSelect:
+		if len(v.Options) == 0 {
+			return &ValidationError{"survey variable " + v.Name + " must have options"}
+		}
+		for _, option := range v.Options {
+			if option == "" {
+				return &ValidationError{"options of survey variable " + v.Name + " can not be empty"}
+			}
+		}
+	default:
+		return &ValidationError{"unknown type of survey variable " + v.Name}
+	}
+
+	if v.Regex != "" {
+		if _, err := regexp.Compile(v.Regex); err != nil {
+			return &ValidationError{"invalid regex of survey variable " + v.Name}
+		}
+	}
+
+	if v.DefaultValue != "" {
+		if _, err := v.ParseValue(v.DefaultValue); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// IsSecret returns true if values of the variable must not be stored or shown.
+func (v *SurveyVar) IsSecret() bool {
+	return v.Type == SurveyVarSecret
+}
+
+func (v *SurveyVar) hasOption(value string) bool {
+	for _, option := range v.Options {
+		if option == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseValue checks the value and converts it to the type of the variable.
+// Values of int and boolean variables can be passed as strings,
+// values of multiselect variables as comma separated strings.
+func (v *SurveyVar) ParseValue(value interface{}) (interface{}, error) {
+	var str string
+	var res interface{}
+
+	switch v.Type {
+	case SurveyVarInt:
+		var n int
+		var err error
+
+		switch val := value.(type) {
+		case float64:
+			if val != math.Trunc(val) {
+				err = strconv.ErrSyntax
+			}
+			n = int(val)
+		case int:
+			n = val
+		case string:
+			n, err = strconv.Atoi(strings.TrimSpace(val))
+		default:
+			err = strconv.ErrSyntax
+		}
+
+		if err != nil {
+			return nil, &ValidationError{"survey variable " + v.Name + " must be an integer"}
+		}
+
+		str = strconv.Itoa(n)
+		res = n
+	case SurveyVarBool:
+		var b bool
+		var err error
+
+		switch val := value.(type) {
+		case bool:
+			b = val
+		case string:
+			b, err = strconv.ParseBool(strings.TrimSpace(val))
+		default:
+			err = strconv.ErrSyntax
+		}
+
+		if err != nil {
+			return nil, &ValidationError{"survey variable " + v.Name + " must be a boolean"}
+		}
+
+		return b, nil
+	case SurveyVarMultiSelect:
+		var items []string
+
+		switch val := value.(type) {
+		case string:
+			for _, item := range strings.Split(val, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+		case []string:
+			items = val
+		case []interface{}:
+			for _, item := range val {
+				s, ok := item.(string)
+				if !ok {
+					return nil, &ValidationError{"survey variable " + v.Name + " must be a list of strings"}
+				}
+				items = append(items, s)
+			}
+		default:
+			return nil, &ValidationError{"survey variable " + v.Name + " must be a list of strings"}
+		}
+
+		selected := make([]string, 0, len(items))
+		for _, item := range items {
+			if !v.hasOption(item) {
+				return nil, &ValidationError{"survey variable " + v.Name + " has no option " + item}
+			}
+			selected = append(selected, item)
+		}
+
+		return selected, nil
+	default:
+		s, ok := value.(string)
+		if !ok {
+			return nil, &ValidationError{"survey variable " + v.Name + " must be a string"}
+		}
+
+		if v.Type == SurveyVarEnum && !v.hasOption(s) {
+			return nil, &ValidationError{"survey variable " + v.Name + " has no option " + s}
+		}
+
+		str = s
+		res = s
+	}
+
+	if v.Regex != "" {
+		re, err := regexp.Compile("^(?:" + v.Regex + ")$")
+		if err != nil || !re.MatchString(str) {
+			return nil, &ValidationError{"value of survey variable " + v.Name + " does not match " + v.Regex}
+		}
+	}
+
+	return res, nil
+}
+
+// isEmptySurveyValue returns true for values which are treated as missing answers.
+func isEmptySurveyValue(value interface{}) bool {
+	switch val := value.(type) {
+	case nil:
+		return true
+	case string:
+		return val == ""
+	case []interface{}:
+		return len(val) == 0
+	case []string:
+		return len(val) == 0
+	}
+	return false
+}
@@ -1,6 +1,8 @@
 package db
 
 import (
+	"encoding/json"
+	"reflect"
 	"time"
 )
 
@@ -13,8 +15,21 @@ const (
 	TaskStoppedStatus  TaskStatus = "stopped"
 	TaskSuccessStatus  TaskStatus = "success"
 	TaskFailStatus     TaskStatus = "error"
+	TaskTimeoutStatus  TaskStatus = "timeout"
+
+	// TaskAwaitingApprovalStatus tasks wait for an approver before they are put to the queue.
+	TaskAwaitingApprovalStatus TaskStatus = "awaiting_approval"
 )
 
+// IsFinished returns true if the task of the status will not run anymore.
+func (s TaskStatus) IsFinished() bool {
+	switch s {
+	case TaskSuccessStatus, TaskFailStatus, TaskStoppedStatus, TaskTimeoutStatus:
+		return true
+	}
+	return false
+}
+
 //Task is a model of a task which will be executed by the runner
 type Task struct {
 	ID         int `db:"id" json:"id"`
@@ -25,6 +40,12 @@ type Task struct {
 	Debug  bool       `db:"debug" json:"debug"`
 
 	DryRun bool `db:"dry_run" json:"dry_run"`
+	// Diff passes --diff to ansible, so the task reports changes it makes to files.
+	// Diffs of dry runs can be reviewed before the real run of the template.
+	Diff bool `db:"diff" json:"diff"`
+	// ValidateOnly runs the syntax check and the lint command of the template
+	// without running the playbook.
+	ValidateOnly bool `db:"validate_only" json:"validate_only"`
 
 	// override variables
 	Playbook    string `db:"playbook" json:"playbook"`
@@ -49,10 +70,110 @@ type Task struct {
 	BuildTaskID *int `db:"build_task_id" json:"build_task_id"`
 
 	// Version is a build version.
-	// This field available only for Build tasks.
+	// This field available only for Build tasks and for rollbacks of Deploy tasks,
+	// which are pinned to the version of the redeployed build.
 	Version *string `db:"version" json:"version"`
 
 	Arguments *string `db:"arguments" json:"arguments"`
+
+	// Timeout overrides maximum runtime of the template in minutes.
+	Timeout *int `db:"timeout" json:"timeout"`
+
+	// Attempt is a number of the task run. It is greater than 1 for automatic retries.
+	Attempt int `db:"attempt" json:"attempt"`
+	// RetryOfTaskID is the ID of the first attempt of the retried task.
+	RetryOfTaskID *int `db:"retry_of_task_id" json:"retry_of_task_id"`
+	// SkipApproval is set by the task pool for automatic retries of tasks which already passed approval.
+	// It is never taken from or returned to users.
+	SkipApproval bool `db:"-" json:"-"`
+
+	// Priority overrides priority of the template.
+	Priority *int `db:"priority" json:"priority"`
+
+	// SurveyValuesJSON used internally for read from database.
+	// Do not use it in your code. Use SurveyValues instead.
+	SurveyValuesJSON *string `db:"survey_values" json:"-"`
+	// SurveyValues are answers to survey variables of the template.
+	// They are checked by ValidateNewTask and passed to the playbook as extra variables.
+	SurveyValues map[string]interface{} `db:"-" json:"survey_values"`
+	// SecretSurveyValues are answers to secret survey variables.
+	// They are kept in memory only and never stored or returned to users.
+	SecretSurveyValues map[string]interface{} `db:"-" json:"-"`
+
+	// PipelineRunID is the run of the pipeline which started the task.
+	PipelineRunID *int `db:"pipeline_run_id" json:"pipeline_run_id"`
+	// PipelineStage is the name of the pipeline stage which the task runs.
+	PipelineStage string `db:"pipeline_stage" json:"pipeline_stage"`
+}
+
+// ResetServerFields clears fields which only the server sets,
+// so they cannot be passed by users creating a task.
+func (task *Task) ResetServerFields() {
+	task.Attempt = 0
+	task.RetryOfTaskID = nil
+	task.PipelineRunID = nil
+	task.PipelineStage = ""
+}
+
+// GetAttempt returns the number of the task run starting from 1.
+func (task *Task) GetAttempt() int {
+	if task.Attempt < 1 {
+		return 1
+	}
+	return task.Attempt
+}
+
+// GetPriority returns priority of the task in the queue.
+func (task *Task) GetPriority(template Template) int {
+	if task.Priority != nil {
+		return *task.Priority
+	}
+	return template.Priority
+}
+
+// GetTimeout returns maximum runtime of the task or zero if the runtime is not limited.
+func (task *Task) GetTimeout(template Template) time.Duration {
+	timeout := template.Timeout
+
+	if task.Timeout != nil {
+		timeout = *task.Timeout
+	}
+
+	return time.Duration(timeout) * time.Minute
+}
+
+// HasSameParams returns true if the other task runs the same template with the same parameters.
+// Survey values must be filled by FillTask.
+func (task *Task) HasSameParams(other Task) bool {
+	if task.TemplateID != other.TemplateID ||
+		task.Playbook != other.Playbook ||
+		task.Environment != other.Environment ||
+		task.Limit != other.Limit {
+		return false
+	}
+
+	if !equalStringPtrs(task.Arguments, other.Arguments) ||
+		!equalStringPtrs(task.CommitHash, other.CommitHash) {
+		return false
+	}
+
+	if (task.BuildTaskID == nil) != (other.BuildTaskID == nil) ||
+		(task.BuildTaskID != nil && *task.BuildTaskID != *other.BuildTaskID) {
+		return false
+	}
+
+	if len(task.SurveyValues) == 0 && len(other.SurveyValues) == 0 {
+		return true
+	}
+
+	return reflect.DeepEqual(task.SurveyValues, other.SurveyValues)
+}
+
+func equalStringPtrs(a *string, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
 }
 
 func (task *Task) GetIncomingVersion(d Store) *string {
@@ -79,6 +200,14 @@ func (task *Task) GetIncomingVersion(d Store) *string {
 }
 
 func (task *Task) ValidateNewTask(template Template) error {
+	if task.Timeout != nil && *task.Timeout < 0 {
+		return &ValidationError{"task timeout can not be negative"}
+	}
+
+	if err := task.ValidateSurveyValues(template); err != nil {
+		return err
+	}
+
 	switch template.Type {
 	case TemplateBuild:
 	case TemplateDeploy:
@@ -87,7 +216,111 @@ func (task *Task) ValidateNewTask(template Template) error {
 	return nil
 }
 
+// ValidateSurveyValues checks answers to survey variables of the template,
+// applies default values and converts the answers to types of the variables.
+// Answers which are passed in Environment by older clients are taken into account.
+// Answers to secret variables are moved to SecretSurveyValues.
+func (task *Task) ValidateSurveyValues(template Template) error {
+	vars := make(map[string]SurveyVar)
+	for _, v := range template.SurveyVars {
+		vars[v.Name] = v
+	}
+
+	for name := range task.SurveyValues {
+		if _, ok := vars[name]; !ok {
+			return &ValidationError{"unknown survey variable " + name}
+		}
+	}
+
+	environment := make(map[string]interface{})
+	if task.Environment != "" {
+		if err := json.Unmarshal([]byte(task.Environment), &environment); err != nil {
+			return &ValidationError{"task environment must be valid JSON"}
+		}
+	}
+
+	values := make(map[string]interface{})
+	secrets := make(map[string]interface{})
+	environmentHasSecrets := false
+
+	for _, v := range template.SurveyVars {
+		value, ok := task.SurveyValues[v.Name]
+		if !ok {
+			value, ok = task.SecretSurveyValues[v.Name]
+		}
+		if !ok {
+			value, ok = environment[v.Name]
+			if ok && v.IsSecret() {
+				delete(environment, v.Name)
+				environmentHasSecrets = true
+			}
+		}
+
+		if !ok || isEmptySurveyValue(value) {
+			if v.DefaultValue == "" {
+				if v.Required {
+					return &ValidationError{"survey variable " + v.Name + " is required"}
+				}
+				continue
+			}
+			value = v.DefaultValue
+		}
+
+		parsed, err := v.ParseValue(value)
+		if err != nil {
+			return err
+		}
+
+		if v.IsSecret() {
+			secrets[v.Name] = parsed
+		} else {
+			values[v.Name] = parsed
+		}
+	}
+
+	if environmentHasSecrets {
+		env, err := json.Marshal(environment)
+		if err != nil {
+			return err
+		}
+		task.Environment = string(env)
+	}
+
+	if len(values) == 0 {
+		values = nil
+	}
+
+	if len(secrets) == 0 {
+		secrets = nil
+	}
+
+	task.SurveyValues = values
+	task.SecretSurveyValues = secrets
+	return nil
+}
+
+func FillTask(task *Task) error {
+	if task.SurveyValuesJSON == nil {
+		return nil
+	}
+	return json.Unmarshal([]byte(*task.SurveyValuesJSON), &task.SurveyValues)
+}
+
+func FillTasks(tasks []Task) (err error) {
+	for i := range tasks {
+		err = FillTask(&tasks[i])
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
 func (task *TaskWithTpl) Fill(d Store) error {
+	if err := FillTask(&task.Task); err != nil {
+		return err
+	}
+
 	if task.BuildTaskID != nil {
 		build, err := d.GetTask(task.ProjectID, *task.BuildTaskID)
 		if err == ErrNotFound {
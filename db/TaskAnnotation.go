@@ -0,0 +1,23 @@
+package db
+
+// TaskAnnotationSource is the pre-flight check which found the problem.
+type TaskAnnotationSource string
+
+const (
+	TaskAnnotationSyntaxCheck TaskAnnotationSource = "syntax_check"
+	TaskAnnotationLint        TaskAnnotationSource = "lint"
+)
+
+// TaskAnnotation is a problem in the playbook found by pre-flight validation of the task.
+type TaskAnnotation struct {
+	ID     int                  `db:"id" json:"id"`
+	TaskID int                  `db:"task_id" json:"task_id"`
+	Source TaskAnnotationSource `db:"source" json:"source"`
+	// File is the path of the file relative to the repository root.
+	File string `db:"file" json:"file"`
+	// Line is the line number in the file starting from 1 or 0 if it is unknown.
+	Line int `db:"line" json:"line"`
+	// Rule is the identifier of the lint rule or empty for syntax errors.
+	Rule    string `db:"rule" json:"rule"`
+	Message string `db:"message" json:"message"`
+}
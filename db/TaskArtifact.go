@@ -0,0 +1,16 @@
+package db
+
+import (
+	"time"
+)
+
+// TaskArtifact is a file produced by the task.
+// Its content is kept by lib.ArtifactStore.
+type TaskArtifact struct {
+	ID     int `db:"id" json:"id"`
+	TaskID int `db:"task_id" json:"task_id"`
+	// Name is the path of the file relative to the repository root.
+	Name    string    `db:"name" json:"name"`
+	Size    int64     `db:"size" json:"size"`
+	Created time.Time `db:"created" json:"created"`
+}
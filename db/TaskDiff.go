@@ -0,0 +1,17 @@
+package db
+
+// TaskDiff is a change which a task of the playbook made or, in check mode, would make on a host.
+// It is reported by the semaphore_diffs callback plugin when the task runs with --diff.
+type TaskDiff struct {
+	ID     int    `db:"id" json:"id"`
+	TaskID int    `db:"task_id" json:"task_id"`
+	Host   string `db:"host" json:"host"`
+	// TaskName is the name of the playbook task which reported the diff.
+	TaskName     string `db:"task_name" json:"task_name"`
+	BeforeHeader string `db:"before_header" json:"before_header"`
+	Before       string `db:"before" json:"before"`
+	AfterHeader  string `db:"after_header" json:"after_header"`
+	After        string `db:"after" json:"after"`
+	// Prepared is a diff formatted by the module itself, e.g. by the template module in check mode.
+	Prepared string `db:"prepared" json:"prepared"`
+}
@@ -0,0 +1,35 @@
+package db
+
+import (
+	"encoding/json"
+)
+
+// TaskHost is the summary of the playbook run on a single host.
+// It is reported by the semaphore_hosts callback plugin at the end of the playbook.
+type TaskHost struct {
+	TaskID      int    `db:"task_id" json:"task_id"`
+	Host        string `db:"host" json:"host"`
+	Ok          int    `db:"ok" json:"ok"`
+	Changed     int    `db:"changed" json:"changed"`
+	Failed      int    `db:"failed" json:"failed"`
+	Unreachable int    `db:"unreachable" json:"unreachable"`
+	Skipped     int    `db:"skipped" json:"skipped"`
+
+	// FailedTasksJSON used internally for read from database.
+	// Do not use it in your code. Use FailedTasks instead.
+	FailedTasksJSON *string  `db:"failed_tasks" json:"-"`
+	FailedTasks     []string `db:"-" json:"failed_tasks"`
+}
+
+func FillTaskHosts(hosts []TaskHost) (err error) {
+	for i := range hosts {
+		if hosts[i].FailedTasksJSON == nil {
+			continue
+		}
+		err = json.Unmarshal([]byte(*hosts[i].FailedTasksJSON), &hosts[i].FailedTasks)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
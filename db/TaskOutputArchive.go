@@ -0,0 +1,48 @@
+package db
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+)
+
+// TaskOutputArchive is the whole output of a finished task stored as a single
+// gzip compressed record instead of one record per line.
+type TaskOutputArchive struct {
+	TaskID int    `db:"task_id" json:"task_id"`
+	Output []byte `db:"output" json:"output"`
+}
+
+// CreateTaskOutputArchive compresses the output records of the task.
+func CreateTaskOutputArchive(taskID int, outputs []TaskOutput) (archive TaskOutputArchive, err error) {
+	var buf bytes.Buffer
+
+	w := gzip.NewWriter(&buf)
+
+	err = json.NewEncoder(w).Encode(outputs)
+	if err != nil {
+		return
+	}
+
+	err = w.Close()
+	if err != nil {
+		return
+	}
+
+	archive.TaskID = taskID
+	archive.Output = buf.Bytes()
+	return
+}
+
+// GetOutputs decompresses the output records.
+func (archive *TaskOutputArchive) GetOutputs() (outputs []TaskOutput, err error) {
+	r, err := gzip.NewReader(bytes.NewReader(archive.Output))
+	if err != nil {
+		return
+	}
+
+	defer r.Close() //nolint: errcheck
+
+	err = json.NewDecoder(r).Decode(&outputs)
+	return
+}
@@ -0,0 +1,185 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTask_GetTimeout(t *testing.T) {
+	tpl := Template{Timeout: 30}
+
+	task := Task{}
+	if task.GetTimeout(tpl) != 30*time.Minute {
+		t.Fatal("template timeout must be used by default")
+	}
+
+	override := 0
+	task.Timeout = &override
+	if task.GetTimeout(tpl) != 0 {
+		t.Fatal("task timeout must override template timeout")
+	}
+
+	override = 5
+	if task.GetTimeout(tpl) != 5*time.Minute {
+		t.Fatal("task timeout must override template timeout")
+	}
+}
+
+func TestTask_ValidateSurveyValues(t *testing.T) {
+	tpl := Template{
+		SurveyVars: []SurveyVar{
+			{Name: "count", Type: SurveyVarInt, Required: true},
+			{Name: "env", Regex: "prod|stage", DefaultValue: "stage"},
+			{Name: "comment"},
+		},
+	}
+
+	task := Task{
+		SurveyValues: map[string]interface{}{"count": "3"},
+	}
+
+	err := task.ValidateSurveyValues(tpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if task.SurveyValues["count"] != 3 || task.SurveyValues["env"] != "stage" {
+		t.Fatal("values must be parsed and defaults applied", task.SurveyValues)
+	}
+
+	if _, ok := task.SurveyValues["comment"]; ok {
+		t.Fatal("optional values without default must be omitted")
+	}
+
+	invalid := []map[string]interface{}{
+		{},
+		{"count": "three"},
+		{"count": 3.5},
+		{"count": 3, "env": "production"},
+		{"count": 3, "unknown": "value"},
+	}
+
+	for _, values := range invalid {
+		task = Task{SurveyValues: values}
+		if err = task.ValidateSurveyValues(tpl); err == nil {
+			t.Fatal("values must be rejected", values)
+		}
+	}
+
+	task = Task{Environment: `{"count": 5}`}
+	if err = task.ValidateSurveyValues(tpl); err != nil || task.SurveyValues["count"] != 5 {
+		t.Fatal("values passed in environment must be accepted", err)
+	}
+}
+
+func TestTask_ValidateSurveyValuesTypes(t *testing.T) {
+	tpl := Template{
+		SurveyVars: []SurveyVar{
+			{Name: "env", Type: SurveyVarEnum, Options: []string{"prod", "stage"}, Required: true},
+			{Name: "dry", Type: SurveyVarBool},
+			{Name: "hosts", Type: SurveyVarMultiSelect, Options: []string{"web", "db"}, DefaultValue: "web"},
+			{Name: "token", Type: SurveyVarSecret},
+		},
+	}
+
+	task := Task{
+		SurveyValues: map[string]interface{}{
+			"env":   "prod",
+			"dry":   "false",
+			"token": "s3cr3t",
+		},
+	}
+
+	err := task.ValidateSurveyValues(tpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if task.SurveyValues["env"] != "prod" || task.SurveyValues["dry"] != false {
+		t.Fatal("values must be parsed", task.SurveyValues)
+	}
+
+	hosts, ok := task.SurveyValues["hosts"].([]string)
+	if !ok || len(hosts) != 1 || hosts[0] != "web" {
+		t.Fatal("multiselect default must be applied", task.SurveyValues)
+	}
+
+	if _, ok = task.SurveyValues["token"]; ok || task.SecretSurveyValues["token"] != "s3cr3t" {
+		t.Fatal("secret values must be moved out of survey values")
+	}
+
+	invalid := []map[string]interface{}{
+		{"env": "dev"},
+		{"env": "prod", "dry": "maybe"},
+		{"env": "prod", "hosts": []interface{}{"web", "cache"}},
+		{"env": "prod", "token": 42},
+	}
+
+	for _, values := range invalid {
+		task = Task{SurveyValues: values}
+		if err = task.ValidateSurveyValues(tpl); err == nil {
+			t.Fatal("values must be rejected", values)
+		}
+	}
+
+	task = Task{Environment: `{"env": "stage", "token": "s3cr3t", "other": 1}`}
+	if err = task.ValidateSurveyValues(tpl); err != nil {
+		t.Fatal(err)
+	}
+
+	if task.SecretSurveyValues["token"] != "s3cr3t" || task.Environment != `{"env":"stage","other":1}` {
+		t.Fatal("secret values must be removed from environment", task.Environment)
+	}
+
+	invalidVars := []SurveyVar{
+		{Name: "env", Type: SurveyVarEnum},
+		{Name: "token", Type: SurveyVarSecret, DefaultValue: "s3cr3t"},
+		{Name: "hosts", Type: SurveyVarMultiSelect, Options: []string{"web"}, DefaultValue: "db"},
+	}
+
+	for _, v := range invalidVars {
+		if err = v.Validate(); err == nil {
+			t.Fatal("survey variable must be rejected", v)
+		}
+	}
+}
+
+func TestTask_HasSameParams(t *testing.T) {
+	args := "[\"-v\"]"
+	task := Task{
+		TemplateID:   1,
+		Limit:        "web",
+		Arguments:    &args,
+		SurveyValues: map[string]interface{}{"count": 3},
+	}
+
+	sameArgs := "[\"-v\"]"
+	checkRun := Task{
+		TemplateID:   1,
+		DryRun:       true,
+		Diff:         true,
+		Limit:        "web",
+		Arguments:    &sameArgs,
+		SurveyValues: map[string]interface{}{"count": 3},
+	}
+
+	if !task.HasSameParams(checkRun) {
+		t.Fatal("tasks with the same parameters must match")
+	}
+
+	checkRun.Limit = "db"
+	if task.HasSameParams(checkRun) {
+		t.Fatal("tasks with different limits must not match")
+	}
+
+	checkRun.Limit = "web"
+	checkRun.SurveyValues = nil
+	if task.HasSameParams(checkRun) {
+		t.Fatal("tasks with different survey values must not match")
+	}
+
+	task.SurveyValues = map[string]interface{}{}
+	if !task.HasSameParams(checkRun) {
+		t.Fatal("empty survey values must match missing ones")
+	}
+}
@@ -2,6 +2,11 @@ package db
 
 import (
 	"encoding/json"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/ansible-semaphore/semaphore/util"
 )
 
 type TemplateType string
@@ -12,11 +17,40 @@ const (
 	TemplateDeploy TemplateType = "deploy"
 )
 
+// TemplateVersionStrategy defines how versions of build tasks are computed.
+type TemplateVersionStrategy string
+
+const (
+	// TemplateVersionIncrement increments the last number of the previous version.
+	TemplateVersionIncrement   TemplateVersionStrategy = ""
+	TemplateVersionSemverMajor TemplateVersionStrategy = "semver_major"
+	TemplateVersionSemverMinor TemplateVersionStrategy = "semver_minor"
+	TemplateVersionSemverPatch TemplateVersionStrategy = "semver_patch"
+	// TemplateVersionDate versions are dates with the number of the build of the day, e.g. 2026.10.17.1.
+	TemplateVersionDate TemplateVersionStrategy = "date"
+	// TemplateVersionGitTag versions are the latest tag of the commit which the task checks out.
+	TemplateVersionGitTag TemplateVersionStrategy = "git_tag"
+)
+
+// TemplateRetryCondition defines which failures of a task cause a retry.
+type TemplateRetryCondition string
+
+const (
+	TemplateRetryOnError       TemplateRetryCondition = ""
+	TemplateRetryOnUnreachable TemplateRetryCondition = "unreachable"
+)
+
 type SurveyVarType string
 
 const (
-	SurveyVarStr TemplateType = ""
-	SurveyVarInt TemplateType = "int"
+	SurveyVarStr  SurveyVarType = ""
+	SurveyVarInt  SurveyVarType = "int"
+	SurveyVarEnum SurveyVarType = "enum"
+	SurveyVarBool SurveyVarType = "boolean"
+	// SurveyVarSecret values are not stored in the database and are masked in task output.
+	SurveyVarSecret SurveyVarType = "secret"
+	// SurveyVarMultiSelect values are lists of options.
+	SurveyVarMultiSelect SurveyVarType = "multiselect"
 )
 
 type SurveyVar struct {
@@ -25,8 +59,51 @@ type SurveyVar struct {
 	Required    bool          `json:"required"`
 	Type        SurveyVarType `json:"type"`
 	Description string        `json:"description"`
+	// DefaultValue is used if the task has no value for the variable.
+	DefaultValue string `json:"default_value,omitempty"`
+	// Regex is a regular expression which the whole value must match.
+	Regex string `json:"regex,omitempty"`
+	// Options are allowed values of enum and multiselect variables.
+	Options []string `json:"options,omitempty"`
+}
+
+// TemplateApproverRole is a project role whose users can approve tasks.
+type TemplateApproverRole string
+
+const (
+	TemplateApproverAdmin  TemplateApproverRole = "admin"
+	TemplateApproverMember TemplateApproverRole = "member"
+)
+
+// TemplateApprovers are users who can approve or reject tasks of the template.
+type TemplateApprovers struct {
+	UserIDs []int                  `json:"user_ids"`
+	Roles   []TemplateApproverRole `json:"roles"`
 }
 
+// TemplateApp is the tool which runs the template.
+type TemplateApp string
+
+const (
+	// TemplateAnsible runs the playbook of the template with ansible-playbook.
+	TemplateAnsible TemplateApp = ""
+	// TemplateCommand runs a script from the repository or a command from PATH.
+	TemplateCommand TemplateApp = "command"
+)
+
+// TemplateConcurrencyMode defines which tasks of the same template can run at the same time.
+type TemplateConcurrencyMode string
+
+const (
+	// TemplateConcurrencySerial runs tasks of the template one by one.
+	TemplateConcurrencySerial TemplateConcurrencyMode = ""
+	// TemplateConcurrencyParallel runs tasks of the template in parallel.
+	TemplateConcurrencyParallel TemplateConcurrencyMode = "parallel"
+	// TemplateConcurrencyPerTarget runs tasks of the template in parallel
+	// unless they have the same inventory and limit.
+	TemplateConcurrencyPerTarget TemplateConcurrencyMode = "per_target"
+)
+
 type TemplateFilter struct {
 	ViewID          *int
 	BuildTemplateID *int
@@ -60,6 +137,9 @@ type Template struct {
 	StartVersion    *string      `db:"start_version" json:"start_version"`
 	BuildTemplateID *int         `db:"build_template_id" json:"build_template_id"`
 
+	// VersionStrategy defines how versions of tasks of the build template are computed.
+	VersionStrategy TemplateVersionStrategy `db:"version_strategy" json:"version_strategy"`
+
 	ViewID *int `db:"view_id" json:"view_id"`
 
 	LastTask *TaskWithTpl `db:"-" json:"last_task"`
@@ -73,6 +153,55 @@ type Template struct {
 	SurveyVars     []SurveyVar `db:"-" json:"survey_vars"`
 
 	SuppressSuccessAlerts bool `db:"suppress_success_alerts" json:"suppress_success_alerts"`
+
+	// Timeout is maximum runtime of a task in minutes. Zero means no limit.
+	Timeout int `db:"timeout" json:"timeout"`
+
+	// RetryCount is how many times a failed task is restarted automatically.
+	RetryCount int `db:"retry_count" json:"retry_count"`
+	// RetryDelay is the delay in seconds before a failed task is restarted.
+	RetryDelay int                    `db:"retry_delay" json:"retry_delay"`
+	RetryOn    TemplateRetryCondition `db:"retry_on" json:"retry_on"`
+
+	// Priority of tasks of the template. Tasks with higher priority are started first.
+	Priority int `db:"priority" json:"priority"`
+
+	// ContainerImage is the image in which ansible runs. Tasks run on the host if it is empty.
+	ContainerImage string `db:"container_image" json:"container_image"`
+
+	// Artifacts is a glob relative to the repository root which matches files
+	// stored after the run. If it matches a directory, all files of the directory are stored.
+	Artifacts string `db:"artifacts" json:"artifacts"`
+
+	// RequireApproval holds new tasks of the deploy template until one of Approvers approves them.
+	RequireApproval bool `db:"require_approval" json:"require_approval"`
+
+	// ApproversJSON used internally for read from database.
+	// Do not use it in your code. Use Approvers instead.
+	ApproversJSON *string           `db:"approvers" json:"-"`
+	Approvers     TemplateApprovers `db:"-" json:"approvers"`
+
+	// ConcurrencyGroupsJSON used internally for read from database.
+	// Do not use it in your code. Use ConcurrencyGroups instead.
+	ConcurrencyGroupsJSON *string `db:"concurrency_groups" json:"-"`
+	// ConcurrencyGroups are names of locks shared by templates of all projects.
+	// A task does not start while a task of another template with the same group runs.
+	ConcurrencyGroups []string `db:"-" json:"concurrency_groups"`
+	// LockInventory prevents tasks of templates with the same inventory from running at the same time.
+	LockInventory bool `db:"lock_inventory" json:"lock_inventory"`
+	// ConcurrencyMode defines if tasks of the template can run in parallel.
+	ConcurrencyMode TemplateConcurrencyMode `db:"concurrency_mode" json:"concurrency_mode"`
+
+	// SyntaxCheck runs ansible-playbook --syntax-check before the playbook.
+	// It is ignored by command templates.
+	SyntaxCheck bool `db:"syntax_check" json:"syntax_check"`
+	// App is the tool which runs the template. Playbook is the script or the command
+	// for command templates.
+	App TemplateApp `db:"app" json:"app"`
+
+	// LintCommand is run in the repository before the playbook, e.g. "ansible-lint -p".
+	// Its arguments are separated by spaces. The task fails if the command fails.
+	LintCommand string `db:"lint_command" json:"lint_command"`
 }
 
 func (tpl *Template) Validate() error {
@@ -84,6 +213,80 @@ func (tpl *Template) Validate() error {
 		return &ValidationError{"template playbook can not be empty"}
 	}
 
+	switch tpl.App {
+	case TemplateAnsible, TemplateCommand:
+	default:
+		return &ValidationError{"unknown template app " + string(tpl.App)}
+	}
+
+	if tpl.Timeout < 0 {
+		return &ValidationError{"template timeout can not be negative"}
+	}
+
+	if tpl.RetryCount < 0 || tpl.RetryDelay < 0 {
+		return &ValidationError{"template retry count and delay can not be negative"}
+	}
+
+	switch tpl.RetryOn {
+	case TemplateRetryOnError, TemplateRetryOnUnreachable:
+	default:
+		return &ValidationError{"unknown template retry condition"}
+	}
+
+	if tpl.Artifacts != "" {
+		if _, err := path.Match(tpl.Artifacts, ""); err != nil || path.IsAbs(tpl.Artifacts) ||
+			strings.HasPrefix(path.Clean(tpl.Artifacts), "..") {
+			return &ValidationError{"template artifacts must be a valid glob relative to the repository"}
+		}
+	}
+
+	if tpl.Type == TemplateBuild {
+		if err := tpl.validateVersionStrategy(); err != nil {
+			return err
+		}
+	}
+
+	if tpl.RequireApproval {
+		if tpl.Type != TemplateDeploy {
+			return &ValidationError{"only deploy templates can require approval"}
+		}
+
+		if len(tpl.Approvers.UserIDs) == 0 && len(tpl.Approvers.Roles) == 0 {
+			return &ValidationError{"template which requires approval must have approvers"}
+		}
+
+		for _, role := range tpl.Approvers.Roles {
+			switch role {
+			case TemplateApproverAdmin, TemplateApproverMember:
+			default:
+				return &ValidationError{"unknown approver role " + string(role)}
+			}
+		}
+	}
+
+	switch tpl.ConcurrencyMode {
+	case TemplateConcurrencySerial, TemplateConcurrencyParallel, TemplateConcurrencyPerTarget:
+	default:
+		return &ValidationError{"unknown concurrency mode " + string(tpl.ConcurrencyMode)}
+	}
+
+	groups := make(map[string]bool)
+	for _, group := range tpl.ConcurrencyGroups {
+		if strings.TrimSpace(group) == "" {
+			return &ValidationError{"concurrency group name can not be empty"}
+		}
+		if groups[group] {
+			return &ValidationError{"concurrency group " + group + " is defined twice"}
+		}
+		groups[group] = true
+	}
+
+	for _, v := range tpl.SurveyVars {
+		if err := v.Validate(); err != nil {
+			return err
+		}
+	}
+
 	if tpl.Arguments != nil {
 		if !json.Valid([]byte(*tpl.Arguments)) {
 			return &ValidationError{"template arguments must be valid JSON"}
@@ -93,6 +296,70 @@ func (tpl *Template) Validate() error {
 	return nil
 }
 
+// GetStartVersion returns the version of the first build of the template.
+func (tpl *Template) GetStartVersion() string {
+	if tpl.StartVersion == nil {
+		return ""
+	}
+	return *tpl.StartVersion
+}
+
+func (tpl *Template) validateVersionStrategy() error {
+	switch tpl.VersionStrategy {
+	case TemplateVersionIncrement, TemplateVersionDate, TemplateVersionGitTag:
+	case TemplateVersionSemverMajor, TemplateVersionSemverMinor, TemplateVersionSemverPatch:
+		if _, ok := util.ParseSemanticVersion(tpl.GetStartVersion()); !ok {
+			return &ValidationError{"start version of the build template must be a semantic version, e.g. 1.0.0"}
+		}
+	default:
+		return &ValidationError{"unknown template version strategy"}
+	}
+
+	return nil
+}
+
+// HasSecretSurveyVars returns true if the template asks for values which are not stored with tasks.
+func (tpl *Template) HasSecretSurveyVars() bool {
+	for _, v := range tpl.SurveyVars {
+		if v.IsSecret() {
+			return true
+		}
+	}
+	return false
+}
+
+// GetLockGroups returns names of locks which tasks of the template hold while they run.
+func (tpl *Template) GetLockGroups() []string {
+	var groups []string
+
+	for _, group := range tpl.ConcurrencyGroups {
+		groups = append(groups, "group:"+group)
+	}
+
+	if tpl.LockInventory {
+		groups = append(groups, "inventory:"+strconv.Itoa(tpl.InventoryID))
+	}
+
+	return groups
+}
+
+// IsApprover returns true if the project user can approve tasks of the template.
+func (tpl *Template) IsApprover(user ProjectUser) bool {
+	for _, id := range tpl.Approvers.UserIDs {
+		if id == user.UserID {
+			return true
+		}
+	}
+
+	for _, role := range tpl.Approvers.Roles {
+		if role == TemplateApproverMember || (role == TemplateApproverAdmin && user.Admin) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func FillTemplates(d Store, templates []Template) (err error) {
 	for i := range templates {
 		tpl := &templates[i]
@@ -128,5 +395,21 @@ func FillTemplate(d Store, template *Template) (err error) {
 		err = json.Unmarshal([]byte(*template.SurveyVarsJSON), &template.SurveyVars)
 	}
 
+	if err != nil {
+		return
+	}
+
+	if template.ApproversJSON != nil {
+		err = json.Unmarshal([]byte(*template.ApproversJSON), &template.Approvers)
+	}
+
+	if err != nil {
+		return
+	}
+
+	if template.ConcurrencyGroupsJSON != nil {
+		err = json.Unmarshal([]byte(*template.ConcurrencyGroupsJSON), &template.ConcurrencyGroups)
+	}
+
 	return
 }
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"testing"
+)
+
+func TestTemplate_IsApprover(t *testing.T) {
+	tpl := Template{
+		Approvers: TemplateApprovers{
+			UserIDs: []int{3},
+			Roles:   []TemplateApproverRole{TemplateApproverAdmin},
+		},
+	}
+
+	if !tpl.IsApprover(ProjectUser{UserID: 3}) {
+		t.Fatal("listed user must be an approver")
+	}
+
+	if !tpl.IsApprover(ProjectUser{UserID: 4, Admin: true}) {
+		t.Fatal("project admin must be an approver")
+	}
+
+	if tpl.IsApprover(ProjectUser{UserID: 4}) {
+		t.Fatal("other users must not be approvers")
+	}
+
+	tpl.Approvers.Roles = []TemplateApproverRole{TemplateApproverMember}
+
+	if !tpl.IsApprover(ProjectUser{UserID: 4}) {
+		t.Fatal("any project user must be an approver")
+	}
+}
+
+func TestTemplate_ValidateApproval(t *testing.T) {
+	tpl := Template{
+		Name:            "Deploy",
+		Playbook:        "deploy.yml",
+		RequireApproval: true,
+		Approvers: TemplateApprovers{
+			Roles: []TemplateApproverRole{TemplateApproverAdmin},
+		},
+	}
+
+	if tpl.Validate() == nil {
+		t.Fatal("only deploy templates can require approval")
+	}
+
+	tpl.Type = TemplateDeploy
+
+	if err := tpl.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	tpl.Approvers.Roles = []TemplateApproverRole{"owner"}
+
+	if tpl.Validate() == nil {
+		t.Fatal("unknown roles must be rejected")
+	}
+
+	tpl.Approvers.Roles = nil
+
+	if tpl.Validate() == nil {
+		t.Fatal("template which requires approval must have approvers")
+	}
+}
@@ -409,6 +409,17 @@ func (d *BoltDb) updateObject(bucketID int, props db.ObjectProps, object interfa
 
 func (d *BoltDb) createObject(bucketID int, props db.ObjectProps, object interface{}) (interface{}, error) {
 	err := d.db.Update(func(tx *bbolt.Tx) error {
+		var err error
+		object, err = d.createObjectTx(tx, bucketID, props, object)
+		return err
+	})
+
+	return object, err
+}
+
+// createObjectTx creates object within the transaction. It allows to create many objects at once.
+func (d *BoltDb) createObjectTx(tx *bbolt.Tx, bucketID int, props db.ObjectProps, object interface{}) (interface{}, error) {
+	err := func() error {
 		b, err := tx.CreateBucketIfNotExists(makeBucketId(props, bucketID))
 
 		if err != nil {
@@ -490,7 +501,7 @@ func (d *BoltDb) createObject(bucketID int, props db.ObjectProps, object interfa
 		}
 
 		return b.Put(objID.ToBytes(), str)
-	})
+	}()
 
 	return object, err
 }
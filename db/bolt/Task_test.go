@@ -3,6 +3,7 @@ package bolt
 import (
 	"github.com/ansible-semaphore/semaphore/db"
 	"testing"
+	"time"
 )
 
 func TestTask_GetVersion(t *testing.T) {
@@ -89,3 +90,360 @@ func TestTask_GetVersion(t *testing.T) {
 		return
 	}
 }
+
+func TestGetTasksByStatus(t *testing.T) {
+	store := CreateTestStore()
+
+	statuses := []db.TaskStatus{
+		db.TaskWaitingStatus,
+		db.TaskSuccessStatus,
+		db.TaskRunningStatus,
+		db.TaskWaitingStatus,
+	}
+
+	for _, status := range statuses {
+		_, err := store.CreateTask(db.Task{
+			ProjectID: 1,
+			Status:    status,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tasks, err := store.GetTasksByStatus([]db.TaskStatus{db.TaskWaitingStatus, db.TaskRunningStatus})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tasks) != 3 {
+		t.Fatal("expected 3 tasks, got", len(tasks))
+	}
+
+	expected := []db.TaskStatus{
+		db.TaskWaitingStatus,
+		db.TaskRunningStatus,
+		db.TaskWaitingStatus,
+	}
+
+	for i, task := range tasks {
+		if task.Status != expected[i] {
+			t.Fatal("tasks must be ordered by creation")
+		}
+		if i > 0 && task.Created.Before(tasks[i-1].Created) {
+			t.Fatal("tasks must be ordered by creation")
+		}
+	}
+}
+
+func TestTaskHosts(t *testing.T) {
+	store := CreateTestStore()
+
+	task, err := store.CreateTask(db.Task{
+		ProjectID: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.CreateTaskHosts(task.ID, []db.TaskHost{
+		{Host: "db1", Ok: 3, Failed: 1, FailedTasks: []string{"Install packages"}},
+		{Host: "web1", Ok: 4, Changed: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hosts, err := store.GetTaskHosts(1, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hosts) != 2 {
+		t.Fatal("expected 2 hosts, got", len(hosts))
+	}
+
+	if hosts[0].TaskID != task.ID || hosts[0].Host != "db1" || hosts[0].Failed != 1 {
+		t.Fatal("unexpected host result", hosts[0])
+	}
+
+	if len(hosts[0].FailedTasks) != 1 || hosts[0].FailedTasks[0] != "Install packages" {
+		t.Fatal("failed tasks must be restored", hosts[0].FailedTasks)
+	}
+
+	_, err = store.GetTaskHosts(2, task.ID)
+	if err != db.ErrNotFound {
+		t.Fatal("hosts of task of another project must not be returned")
+	}
+}
+
+func TestTaskDiffs(t *testing.T) {
+	store := CreateTestStore()
+
+	task, err := store.CreateTask(db.Task{
+		ProjectID: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.CreateTaskDiffs(task.ID, []db.TaskDiff{
+		{Host: "web1", TaskName: "Write config", Before: "a=1\n", After: "a=2\n"},
+		{Host: "db1", TaskName: "Write config", Prepared: "+b=1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diffs, err := store.GetTaskDiffs(1, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(diffs) != 2 {
+		t.Fatal("expected 2 diffs, got", len(diffs))
+	}
+
+	if diffs[0].TaskID != task.ID || diffs[0].Host != "web1" || diffs[0].After != "a=2\n" {
+		t.Fatal("diffs must be returned in order of reporting", diffs[0])
+	}
+
+	_, err = store.GetTaskDiffs(2, task.ID)
+	if err != db.ErrNotFound {
+		t.Fatal("diffs of task of another project must not be returned")
+	}
+}
+
+func TestCreateTaskOutputs(t *testing.T) {
+	store := CreateTestStore()
+
+	task, err := store.CreateTask(db.Task{
+		ProjectID: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+
+	err = store.CreateTaskOutputs([]db.TaskOutput{
+		{TaskID: task.ID, Output: "first", Time: now},
+		{TaskID: task.ID, Output: "second", Time: now.Add(time.Second)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputs, err := store.GetTaskOutputs(1, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(outputs) != 2 || outputs[0].Output != "first" || outputs[1].Output != "second" {
+		t.Fatal("unexpected outputs", outputs)
+	}
+}
+
+func TestTaskOutputsRetention(t *testing.T) {
+	store := CreateTestStore()
+
+	now := time.Now()
+	end := now.Add(-time.Hour)
+
+	finished, err := store.CreateTask(db.Task{
+		ProjectID: 1,
+		Status:    db.TaskSuccessStatus,
+		End:       &end,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	running, err := store.CreateTask(db.Task{
+		ProjectID: 1,
+		Status:    db.TaskRunningStatus,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, task := range []db.Task{finished, running} {
+		err = store.CreateTaskOutputs([]db.TaskOutput{
+			{TaskID: task.ID, Output: "first", Time: end.Add(-time.Second)},
+			{TaskID: task.ID, Output: "second", Time: end},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = store.CompressTaskOutputsBefore(1, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputs, err := store.GetTaskOutputs(1, finished.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(outputs) != 2 || outputs[0].Output != "first" || outputs[1].Output != "second" {
+		t.Fatal("compressed outputs must be restored", outputs)
+	}
+
+	err = store.DeleteTaskOutputsBefore(1, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputs, err = store.GetTaskOutputs(1, finished.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(outputs) != 0 {
+		t.Fatal("outputs of finished task must be deleted", outputs)
+	}
+
+	outputs, err = store.GetTaskOutputs(1, running.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(outputs) != 2 {
+		t.Fatal("outputs of running task must be kept", outputs)
+	}
+
+	err = store.DeleteTaskWithOutputs(1, finished.ID)
+	if err != nil {
+		t.Fatal("task without outputs must be deleted", err)
+	}
+}
+
+func TestTaskArtifacts(t *testing.T) {
+	store := CreateTestStore()
+
+	task, err := store.CreateTask(db.Task{
+		ProjectID: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"reports/b.csv", "reports/a.csv"} {
+		_, err = store.CreateTaskArtifact(db.TaskArtifact{TaskID: task.ID, Name: name, Size: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	artifacts, err := store.GetTaskArtifacts(1, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(artifacts) != 2 || artifacts[0].Name != "reports/a.csv" {
+		t.Fatal("artifacts must be ordered by name", artifacts)
+	}
+
+	artifact, err := store.GetTaskArtifact(1, task.ID, artifacts[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if artifact.Name != "reports/b.csv" {
+		t.Fatal("unexpected artifact", artifact)
+	}
+
+	_, err = store.GetTaskArtifact(2, task.ID, artifact.ID)
+	if err != db.ErrNotFound {
+		t.Fatal("artifacts of task of another project must not be returned")
+	}
+}
+
+func TestGetTemplateTasksByStatus(t *testing.T) {
+	store := CreateTestStore()
+
+	build, err := store.CreateTemplate(db.Template{
+		ProjectID: 0,
+		Type:      db.TemplateBuild,
+		Name:      "Build",
+		Playbook:  "build.yml",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, status := range []db.TaskStatus{db.TaskSuccessStatus, db.TaskFailStatus, db.TaskSuccessStatus} {
+		_, err = store.CreateTask(db.Task{
+			ProjectID:  0,
+			TemplateID: build.ID,
+			Status:     status,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	builds, err := store.GetTemplateTasksByStatus(0, build.ID, db.TaskSuccessStatus, db.RetrieveQueryParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(builds) != 2 {
+		t.Fatal("expected 2 successful builds, got", len(builds))
+	}
+
+	for _, b := range builds {
+		if b.Status != db.TaskSuccessStatus {
+			t.Fatal("unexpected build status", b.Status)
+		}
+	}
+}
+
+func TestGetTemplateBuilds(t *testing.T) {
+	store := CreateTestStore()
+
+	build, err := store.CreateTemplate(db.Template{
+		ProjectID: 0,
+		Type:      db.TemplateBuild,
+		Name:      "Build",
+		Playbook:  "build.yml",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.CreateTask(db.Task{
+		ProjectID:  0,
+		TemplateID: build.ID,
+		Status:     db.TaskSuccessStatus,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the newest task is a validation, it must not take the place of the build on the page
+	_, err = store.CreateTask(db.Task{
+		ProjectID:    0,
+		TemplateID:   build.ID,
+		Status:       db.TaskSuccessStatus,
+		ValidateOnly: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	builds, err := store.GetTemplateBuilds(0, build.ID, db.RetrieveQueryParams{Count: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(builds) != 1 {
+		t.Fatal("expected 1 build, got", len(builds))
+	}
+
+	if builds[0].ValidateOnly {
+		t.Fatal("validations must not be returned as builds")
+	}
+}
@@ -0,0 +1,98 @@
+package bolt
+
+import (
+	"github.com/ansible-semaphore/semaphore/db"
+	"sort"
+	"time"
+)
+
+func (d *BoltDb) GetPipelines(projectID int) (pipelines []db.Pipeline, err error) {
+	err = d.getObjects(projectID, db.PipelineProps, db.RetrieveQueryParams{}, nil, &pipelines)
+	if err != nil {
+		return
+	}
+	err = db.FillPipelines(pipelines)
+	return
+}
+
+func (d *BoltDb) GetPipeline(projectID int, pipelineID int) (pipeline db.Pipeline, err error) {
+	err = d.getObject(projectID, db.PipelineProps, intObjectID(pipelineID), &pipeline)
+	if err != nil {
+		return
+	}
+	err = db.FillPipeline(&pipeline)
+	return
+}
+
+func (d *BoltDb) CreatePipeline(pipeline db.Pipeline) (db.Pipeline, error) {
+	pipeline.StagesJSON = db.ObjectToJSON(pipeline.Stages)
+	newPipeline, err := d.createObject(pipeline.ProjectID, db.PipelineProps, pipeline)
+	return newPipeline.(db.Pipeline), err
+}
+
+func (d *BoltDb) UpdatePipeline(pipeline db.Pipeline) error {
+	pipeline.StagesJSON = db.ObjectToJSON(pipeline.Stages)
+	return d.updateObject(pipeline.ProjectID, db.PipelineProps, pipeline)
+}
+
+func (d *BoltDb) DeletePipeline(projectID int, pipelineID int) error {
+	runs, err := d.GetPipelineRuns(projectID, pipelineID, db.RetrieveQueryParams{})
+	if err != nil {
+		return err
+	}
+
+	for _, run := range runs {
+		err = d.deleteObject(projectID, db.PipelineRunProps, intObjectID(run.ID), nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return d.deleteObject(projectID, db.PipelineProps, intObjectID(pipelineID), nil)
+}
+
+func (d *BoltDb) CreatePipelineRun(run db.PipelineRun) (db.PipelineRun, error) {
+	run.Created = time.Now()
+	newRun, err := d.createObject(run.ProjectID, db.PipelineRunProps, run)
+	return newRun.(db.PipelineRun), err
+}
+
+func (d *BoltDb) UpdatePipelineRun(run db.PipelineRun) error {
+	return d.updateObject(run.ProjectID, db.PipelineRunProps, run)
+}
+
+func (d *BoltDb) GetPipelineRun(projectID int, runID int) (run db.PipelineRun, err error) {
+	err = d.getObject(projectID, db.PipelineRunProps, intObjectID(runID), &run)
+	return
+}
+
+func (d *BoltDb) GetPipelineRuns(projectID int, pipelineID int, params db.RetrieveQueryParams) (runs []db.PipelineRun, err error) {
+	// runs are stored newest first
+	err = d.getObjects(projectID, db.PipelineRunProps, params, func(obj interface{}) bool {
+		return obj.(db.PipelineRun).PipelineID == pipelineID
+	}, &runs)
+	return
+}
+
+func (d *BoltDb) GetPipelineRunTasks(projectID int, runID int) (tasks []db.Task, err error) {
+	err = d.getObjects(0, db.TaskProps, db.RetrieveQueryParams{}, func(tsk interface{}) bool {
+		task := tsk.(db.Task)
+		return task.ProjectID == projectID && task.PipelineRunID != nil && *task.PipelineRunID == runID
+	}, &tasks)
+
+	if err != nil {
+		return
+	}
+
+	// tasks are stored newest first
+	for i, j := 0, len(tasks)-1; i < j; i, j = i+1, j-1 {
+		tasks[i], tasks[j] = tasks[j], tasks[i]
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].Created.Before(tasks[j].Created)
+	})
+
+	err = db.FillTasks(tasks)
+	return
+}
@@ -0,0 +1,85 @@
+package bolt
+
+import (
+	"github.com/ansible-semaphore/semaphore/db"
+	"testing"
+)
+
+func TestPipelineRuns(t *testing.T) {
+	store := CreateTestStore()
+
+	pipeline, err := store.CreatePipeline(db.Pipeline{
+		ProjectID: 1,
+		Name:      "Release",
+		Stages: []db.PipelineStage{
+			{Name: "build", TemplateID: 1},
+			{Name: "deploy", TemplateID: 2, Needs: []string{"build"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pipeline, err = store.GetPipeline(1, pipeline.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pipeline.Stages) != 2 || pipeline.Stages[1].Needs[0] != "build" {
+		t.Fatal("stages must be stored", pipeline.Stages)
+	}
+
+	var runs []db.PipelineRun
+
+	for i := 0; i < 2; i++ {
+		var run db.PipelineRun
+		run, err = store.CreatePipelineRun(db.PipelineRun{
+			ProjectID:  1,
+			PipelineID: pipeline.ID,
+			Status:     db.TaskRunningStatus,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		runs = append(runs, run)
+	}
+
+	for _, stage := range []string{"build", "deploy"} {
+		_, err = store.CreateTask(db.Task{
+			ProjectID:     1,
+			PipelineRunID: &runs[0].ID,
+			PipelineStage: stage,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tasks, err := store.GetPipelineRunTasks(1, runs[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tasks) != 2 || tasks[0].PipelineStage != "build" {
+		t.Fatal("tasks of the run must be ordered by creation time", tasks)
+	}
+
+	found, err := store.GetPipelineRuns(1, pipeline.ID, db.RetrieveQueryParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(found) != 2 || found[0].ID != runs[1].ID {
+		t.Fatal("runs must be ordered from newest", found)
+	}
+
+	err = store.DeletePipeline(1, pipeline.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.GetPipelineRun(1, runs[0].ID)
+	if err != db.ErrNotFound {
+		t.Fatal("runs must be deleted with the pipeline")
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 func (d *BoltDb) CreateProject(project db.Project) (db.Project, error) {
 	project.Created = time.Now()
+	project.LogMaskPatternsJSON = db.ObjectToJSON(project.LogMaskPatterns)
 
 	newProject, err := d.createObject(0, db.ProjectProps, project)
 
@@ -28,6 +29,12 @@ func (d *BoltDb) GetProjects(userID int) (projects []db.Project, err error) {
 		return
 	}
 
+	err = db.FillProjects(allProjects)
+
+	if err != nil {
+		return
+	}
+
 	for _, v := range allProjects {
 		_, err2 := d.GetProjectUser(v.ID, userID)
 		if err2 == nil {
@@ -41,8 +48,25 @@ func (d *BoltDb) GetProjects(userID int) (projects []db.Project, err error) {
 	return
 }
 
+func (d *BoltDb) GetAllProjects() (projects []db.Project, err error) {
+	err = d.getObjects(0, db.ProjectProps, db.RetrieveQueryParams{}, nil, &projects)
+
+	if err != nil {
+		return
+	}
+
+	err = db.FillProjects(projects)
+	return
+}
+
 func (d *BoltDb) GetProject(projectID int) (project db.Project, err error) {
 	err = d.getObject(0, db.ProjectProps, intObjectID(projectID), &project)
+
+	if err != nil {
+		return
+	}
+
+	err = db.FillProject(&project)
 	return
 }
 
@@ -51,5 +75,6 @@ func (d *BoltDb) DeleteProject(projectID int) error {
 }
 
 func (d *BoltDb) UpdateProject(project db.Project) error {
+	project.LogMaskPatternsJSON = db.ObjectToJSON(project.LogMaskPatterns)
 	return d.updateObject(0, db.ProjectProps, project)
 }
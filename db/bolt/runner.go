@@ -0,0 +1,25 @@
+package bolt
+
+import "github.com/ansible-semaphore/semaphore/db"
+
+func (d *BoltDb) GetRunner(runnerID int) (runner db.Runner, err error) {
+	err = d.getObject(0, db.RunnerProps, intObjectID(runnerID), &runner)
+	return
+}
+
+func (d *BoltDb) GetRunners() (runners []db.Runner, err error) {
+	err = d.getObjects(0, db.RunnerProps, db.RetrieveQueryParams{}, nil, &runners)
+	return
+}
+
+func (d *BoltDb) CreateRunner(runner db.Runner) (db.Runner, error) {
+	newRunner, err := d.createObject(0, db.RunnerProps, runner)
+	if err != nil {
+		return db.Runner{}, err
+	}
+	return newRunner.(db.Runner), nil
+}
+
+func (d *BoltDb) DeleteRunner(runnerID int) error {
+	return d.deleteObject(0, db.RunnerProps, intObjectID(runnerID), nil)
+}
@@ -3,11 +3,13 @@ package bolt
 import (
 	"github.com/ansible-semaphore/semaphore/db"
 	"go.etcd.io/bbolt"
+	"sort"
 	"time"
 )
 
 func (d *BoltDb) CreateTask(task db.Task) (newTask db.Task, err error) {
 	task.Created = time.Now()
+	task.SurveyValuesJSON = db.ObjectToJSON(task.SurveyValues)
 	res, err := d.createObject(0, db.TaskProps, task)
 	if err != nil {
 		return
@@ -28,7 +30,19 @@ func (d *BoltDb) CreateTaskOutput(output db.TaskOutput) (db.TaskOutput, error) {
 	return newOutput.(db.TaskOutput), nil
 }
 
-func (d *BoltDb) getTasks(projectID int, templateID *int, params db.RetrieveQueryParams) (tasksWithTpl []db.TaskWithTpl, err error) {
+func (d *BoltDb) CreateTaskOutputs(outputs []db.TaskOutput) error {
+	return d.db.Update(func(tx *bbolt.Tx) error {
+		for _, output := range outputs {
+			_, err := d.createObjectTx(tx, output.TaskID, db.TaskOutputProps, output)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (d *BoltDb) getTasks(projectID int, templateID *int, status *db.TaskStatus, excludeValidations bool, params db.RetrieveQueryParams) (tasksWithTpl []db.TaskWithTpl, err error) {
 	var tasks []db.Task
 
 	err = d.getObjects(0, db.TaskProps, params, func(tsk interface{}) bool {
@@ -42,6 +56,14 @@ func (d *BoltDb) getTasks(projectID int, templateID *int, params db.RetrieveQuer
 			return false
 		}
 
+		if status != nil && task.Status != *status {
+			return false
+		}
+
+		if excludeValidations && task.ValidateOnly {
+			return false
+		}
+
 		return true
 	}, &tasks)
 
@@ -100,15 +122,55 @@ func (d *BoltDb) GetTask(projectID int, taskID int) (task db.Task, err error) {
 		return
 	}
 
+	err = db.FillTask(&task)
+	return
+}
+
+func (d *BoltDb) GetTasksByStatus(statuses []db.TaskStatus) (tasks []db.Task, err error) {
+	err = d.getObjects(0, db.TaskProps, db.RetrieveQueryParams{}, func(tsk interface{}) bool {
+		task := tsk.(db.Task)
+
+		for _, status := range statuses {
+			if task.Status == status {
+				return true
+			}
+		}
+
+		return false
+	}, &tasks)
+
+	if err != nil {
+		return
+	}
+
+	// tasks are stored newest first
+	for i, j := 0, len(tasks)-1; i < j; i, j = i+1, j-1 {
+		tasks[i], tasks[j] = tasks[j], tasks[i]
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].Created.Before(tasks[j].Created)
+	})
+
+	err = db.FillTasks(tasks)
 	return
 }
 
 func (d *BoltDb) GetTemplateTasks(projectID int, templateID int, params db.RetrieveQueryParams) ([]db.TaskWithTpl, error) {
-	return d.getTasks(projectID, &templateID, params)
+	return d.getTasks(projectID, &templateID, nil, false, params)
+}
+
+func (d *BoltDb) GetTemplateTasksByStatus(projectID int, templateID int, status db.TaskStatus, params db.RetrieveQueryParams) ([]db.TaskWithTpl, error) {
+	return d.getTasks(projectID, &templateID, &status, false, params)
+}
+
+func (d *BoltDb) GetTemplateBuilds(projectID int, templateID int, params db.RetrieveQueryParams) ([]db.TaskWithTpl, error) {
+	status := db.TaskSuccessStatus
+	return d.getTasks(projectID, &templateID, &status, true, params)
 }
 
 func (d *BoltDb) GetProjectTasks(projectID int, params db.RetrieveQueryParams) ([]db.TaskWithTpl, error) {
-	return d.getTasks(projectID, nil, params)
+	return d.getTasks(projectID, nil, nil, false, params)
 }
 
 func (d *BoltDb) deleteTaskWithOutputs(projectID int, taskID int, tx *bbolt.Tx) (err error) {
@@ -123,7 +185,39 @@ func (d *BoltDb) deleteTaskWithOutputs(projectID int, taskID int, tx *bbolt.Tx)
 		return
 	}
 
-	return tx.DeleteBucket(makeBucketId(db.TaskOutputProps, taskID))
+	err = tx.DeleteBucket(makeBucketId(db.TaskHostProps, taskID))
+	if err != nil && err != bbolt.ErrBucketNotFound {
+		return
+	}
+
+	err = tx.DeleteBucket(makeBucketId(db.TaskArtifactProps, taskID))
+	if err != nil && err != bbolt.ErrBucketNotFound {
+		return
+	}
+
+	err = tx.DeleteBucket(makeBucketId(db.TaskDiffProps, taskID))
+	if err != nil && err != bbolt.ErrBucketNotFound {
+		return
+	}
+
+	err = tx.DeleteBucket(makeBucketId(db.TaskAnnotationProps, taskID))
+	if err != nil && err != bbolt.ErrBucketNotFound {
+		return
+	}
+
+	return deleteTaskOutputs(tx, taskID)
+}
+
+// deleteTaskOutputs removes output records and the output archive of the task.
+func deleteTaskOutputs(tx *bbolt.Tx, taskID int) error {
+	for _, props := range []db.ObjectProps{db.TaskOutputProps, db.TaskOutputArchiveProps} {
+		err := tx.DeleteBucket(makeBucketId(props, taskID))
+		if err != nil && err != bbolt.ErrBucketNotFound {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (d *BoltDb) DeleteTaskWithOutputs(projectID int, taskID int) error {
@@ -140,7 +234,242 @@ func (d *BoltDb) GetTaskOutputs(projectID int, taskID int) (outputs []db.TaskOut
 		return
 	}
 
-	err = d.getObjects(taskID, db.TaskOutputProps, db.RetrieveQueryParams{}, nil, &outputs)
+	var archives []db.TaskOutputArchive
+
+	err = d.getObjects(taskID, db.TaskOutputArchiveProps, db.RetrieveQueryParams{}, nil, &archives)
+	if err != nil {
+		return
+	}
+
+	for _, archive := range archives {
+		var archived []db.TaskOutput
+		archived, err = archive.GetOutputs()
+		if err != nil {
+			return
+		}
+		outputs = append(outputs, archived...)
+	}
+
+	var records []db.TaskOutput
+
+	err = d.getObjects(taskID, db.TaskOutputProps, db.RetrieveQueryParams{}, nil, &records)
+
+	outputs = append(outputs, records...)
+	return
+}
+
+// getFinishedTasks returns tasks of the project which finished before the time.
+func (d *BoltDb) getFinishedTasks(projectID int, before time.Time) (tasks []db.Task, err error) {
+	err = d.getObjects(0, db.TaskProps, db.RetrieveQueryParams{}, func(tsk interface{}) bool {
+		task := tsk.(db.Task)
+		return task.ProjectID == projectID && task.End != nil && task.End.Before(before)
+	}, &tasks)
+	return
+}
+
+func (d *BoltDb) DeleteTaskOutputsBefore(projectID int, before time.Time) error {
+	tasks, err := d.getFinishedTasks(projectID, before)
+	if err != nil {
+		return err
+	}
+
+	return d.db.Update(func(tx *bbolt.Tx) error {
+		for _, task := range tasks {
+			err2 := deleteTaskOutputs(tx, task.ID)
+			if err2 != nil {
+				return err2
+			}
+		}
+		return nil
+	})
+}
+
+func (d *BoltDb) CompressTaskOutputsBefore(projectID int, before time.Time) error {
+	tasks, err := d.getFinishedTasks(projectID, before)
+	if err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		var records []db.TaskOutput
+
+		err = d.getObjects(task.ID, db.TaskOutputProps, db.RetrieveQueryParams{}, nil, &records)
+		if err != nil {
+			return err
+		}
+
+		if len(records) == 0 {
+			continue
+		}
+
+		var outputs []db.TaskOutput
+		outputs, err = d.GetTaskOutputs(projectID, task.ID)
+		if err != nil {
+			return err
+		}
+
+		var archive db.TaskOutputArchive
+		archive, err = db.CreateTaskOutputArchive(task.ID, outputs)
+		if err != nil {
+			return err
+		}
+
+		err = d.db.Update(func(tx *bbolt.Tx) error {
+			err2 := deleteTaskOutputs(tx, task.ID)
+			if err2 != nil {
+				return err2
+			}
+			_, err2 = d.createObjectTx(tx, task.ID, db.TaskOutputArchiveProps, archive)
+			return err2
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *BoltDb) CreateTaskHosts(taskID int, hosts []db.TaskHost) error {
+	for _, host := range hosts {
+		host.TaskID = taskID
+		host.FailedTasksJSON = db.ObjectToJSON(host.FailedTasks)
+
+		_, err := d.createObject(taskID, db.TaskHostProps, host)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *BoltDb) CreateTaskDiffs(taskID int, diffs []db.TaskDiff) error {
+	for _, diff := range diffs {
+		diff.TaskID = taskID
+
+		_, err := d.createObject(taskID, db.TaskDiffProps, diff)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *BoltDb) CreateTaskAnnotations(taskID int, annotations []db.TaskAnnotation) error {
+	for _, annotation := range annotations {
+		annotation.TaskID = taskID
+
+		_, err := d.createObject(taskID, db.TaskAnnotationProps, annotation)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *BoltDb) CreateTaskArtifact(artifact db.TaskArtifact) (db.TaskArtifact, error) {
+	artifact.Created = time.Now()
+
+	newArtifact, err := d.createObject(artifact.TaskID, db.TaskArtifactProps, artifact)
+	if err != nil {
+		return db.TaskArtifact{}, err
+	}
+
+	return newArtifact.(db.TaskArtifact), nil
+}
+
+func (d *BoltDb) GetTaskArtifacts(projectID int, taskID int) (artifacts []db.TaskArtifact, err error) {
+	// check if task exists in the project
+	_, err = d.GetTask(projectID, taskID)
+
+	if err != nil {
+		return
+	}
+
+	err = d.getObjects(taskID, db.TaskArtifactProps, db.RetrieveQueryParams{}, nil, &artifacts)
+
+	if err != nil {
+		return
+	}
+
+	sort.SliceStable(artifacts, func(i, j int) bool {
+		return artifacts[i].Name < artifacts[j].Name
+	})
+
+	return
+}
+
+func (d *BoltDb) GetTaskArtifact(projectID int, taskID int, artifactID int) (artifact db.TaskArtifact, err error) {
+	// check if task exists in the project
+	_, err = d.GetTask(projectID, taskID)
+
+	if err != nil {
+		return
+	}
+
+	err = d.getObject(taskID, db.TaskArtifactProps, intObjectID(artifactID), &artifact)
+	return
+}
+
+func (d *BoltDb) GetTaskHosts(projectID int, taskID int) (hosts []db.TaskHost, err error) {
+	// check if task exists in the project
+	_, err = d.GetTask(projectID, taskID)
+
+	if err != nil {
+		return
+	}
+
+	err = d.getObjects(taskID, db.TaskHostProps, db.RetrieveQueryParams{}, nil, &hosts)
+
+	if err != nil {
+		return
+	}
+
+	err = db.FillTaskHosts(hosts)
+	return
+}
+
+func (d *BoltDb) GetTaskDiffs(projectID int, taskID int) (diffs []db.TaskDiff, err error) {
+	// check if task exists in the project
+	_, err = d.GetTask(projectID, taskID)
+
+	if err != nil {
+		return
+	}
+
+	err = d.getObjects(taskID, db.TaskDiffProps, db.RetrieveQueryParams{}, nil, &diffs)
+
+	if err != nil {
+		return
+	}
+
+	sort.SliceStable(diffs, func(i, j int) bool {
+		return diffs[i].ID < diffs[j].ID
+	})
+
+	return
+}
+
+func (d *BoltDb) GetTaskAnnotations(projectID int, taskID int) (annotations []db.TaskAnnotation, err error) {
+	// check if task exists in the project
+	_, err = d.GetTask(projectID, taskID)
+
+	if err != nil {
+		return
+	}
+
+	err = d.getObjects(taskID, db.TaskAnnotationProps, db.RetrieveQueryParams{}, nil, &annotations)
+
+	if err != nil {
+		return
+	}
+
+	sort.SliceStable(annotations, func(i, j int) bool {
+		return annotations[i].ID < annotations[j].ID
+	})
 
 	return
 }
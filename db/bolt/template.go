@@ -13,6 +13,8 @@ func (d *BoltDb) CreateTemplate(template db.Template) (newTemplate db.Template,
 	}
 
 	template.SurveyVarsJSON = db.ObjectToJSON(template.SurveyVars)
+	template.ApproversJSON = db.ObjectToJSON(template.Approvers)
+	template.ConcurrencyGroupsJSON = db.ObjectToJSON(template.ConcurrencyGroups)
 	newTpl, err := d.createObject(template.ProjectID, db.TemplateProps, template)
 	if err != nil {
 		return
@@ -30,6 +32,8 @@ func (d *BoltDb) UpdateTemplate(template db.Template) error {
 	}
 
 	template.SurveyVarsJSON = db.ObjectToJSON(template.SurveyVars)
+	template.ApproversJSON = db.ObjectToJSON(template.Approvers)
+	template.ConcurrencyGroupsJSON = db.ObjectToJSON(template.ConcurrencyGroups)
 	return d.updateObject(template.ProjectID, db.TemplateProps, template)
 }
 
@@ -16,6 +16,7 @@ var (
 	dateTimeTypeRE  = regexp.MustCompile(`(?i)\bdatetime\b`)
 	tinyintRE       = regexp.MustCompile(`(?i)\btinyint\b`)
 	longtextRE      = regexp.MustCompile(`(?i)\blongtext\b`)
+	longblobRE      = regexp.MustCompile(`(?i)\blongblob\b`)
 	ifExistsRE      = regexp.MustCompile(`(?i)\bif exists\b`)
 	changeRE        = regexp.MustCompile(`^alter table \x60(\w+)\x60 change \x60(\w+)\x60 \x60(\w+)\x60 ([\w\(\)]+)( not null)?$`)
 	//dropForeignKeyRE  = regexp.MustCompile(`^alter table \x60(\w+)\x60 drop foreign key \x60(\w+)\x60 /\* postgres:\x60(\w*)\x60 mysql:\x60(\w*)\x60 \*/$`)
@@ -85,6 +86,7 @@ func (d *SqlDb) prepareMigration(query string) string {
 		query = dateTimeTypeRE.ReplaceAllString(query, "timestamp")
 		query = tinyintRE.ReplaceAllString(query, "smallint")
 		query = longtextRE.ReplaceAllString(query, "text")
+		query = longblobRE.ReplaceAllString(query, "bytea")
 		query = serialRE.ReplaceAllString(query, "serial primary key")
 		query = dropForeignKey2RE.ReplaceAllString(query, "drop constraint")
 		query = identifierQuoteRE.ReplaceAllString(query, "\"")
@@ -0,0 +1,4 @@
+create table `runner` (
+	`id` integer primary key autoincrement,
+	`token` varchar(255) not null
+);
@@ -0,0 +1,3 @@
+alter table `project__template` add column `timeout` int not null default 0;
+
+alter table `task` add column `timeout` int null;
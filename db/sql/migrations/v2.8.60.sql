@@ -0,0 +1,6 @@
+alter table `project__template` add column `retry_count` int not null default 0;
+alter table `project__template` add column `retry_delay` int not null default 0;
+alter table `project__template` add column `retry_on` varchar(20) not null default '';
+
+alter table `task` add column `attempt` int not null default 1;
+alter table `task` add column `retry_of_task_id` int null;
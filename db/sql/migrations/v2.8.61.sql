@@ -0,0 +1,3 @@
+alter table `project__template` add column `priority` int not null default 0;
+
+alter table `task` add column `priority` int null;
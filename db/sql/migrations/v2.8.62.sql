@@ -0,0 +1 @@
+alter table `project__template` add column `container_image` varchar(255) not null default '';
@@ -0,0 +1,13 @@
+create table `task__host` (
+	`task_id` int not null,
+	`host` varchar(255) not null,
+	`ok` int not null default 0,
+	`changed` int not null default 0,
+	`failed` int not null default 0,
+	`unreachable` int not null default 0,
+	`skipped` int not null default 0,
+	`failed_tasks` text,
+
+	unique (`task_id`, `host`),
+	foreign key (`task_id`) references task(`id`) on delete cascade
+);
@@ -0,0 +1,9 @@
+alter table `project` add `max_tasks_per_template` int not null default 0;
+alter table `project` add `output_retention_days` int not null default 0;
+
+create table `task__output_archive` (
+	`task_id` int not null primary key,
+	`output` longblob not null,
+
+	foreign key (`task_id`) references task(`id`) on delete cascade
+);
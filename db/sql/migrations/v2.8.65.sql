@@ -0,0 +1 @@
+alter table `project` add `log_mask_patterns` text;
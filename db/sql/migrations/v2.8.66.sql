@@ -0,0 +1,11 @@
+alter table `project__template` add `artifacts` varchar(255) not null default '';
+
+create table `task__artifact` (
+	`id` integer primary key autoincrement,
+	`task_id` int not null,
+	`name` varchar(1000) not null,
+	`size` bigint not null default 0,
+	`created` datetime not null,
+
+	foreign key (`task_id`) references task(`id`) on delete cascade
+);
@@ -0,0 +1 @@
+alter table `task` add `survey_values` text;
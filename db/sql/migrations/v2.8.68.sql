@@ -0,0 +1,27 @@
+create table `project__pipeline` (
+	`id` integer primary key autoincrement,
+	`project_id` int not null,
+	`name` varchar(100) not null,
+	`description` text,
+	`stages` text,
+
+	foreign key (`project_id`) references project(`id`) on delete cascade
+);
+
+create table `project__pipeline_run` (
+	`id` integer primary key autoincrement,
+	`project_id` int not null,
+	`pipeline_id` int not null,
+	`user_id` int,
+	`status` varchar(255) not null,
+	`environment` longtext,
+	`created` datetime not null,
+	`end` datetime,
+
+	foreign key (`project_id`) references project(`id`) on delete cascade,
+	foreign key (`pipeline_id`) references project__pipeline(`id`) on delete cascade,
+	foreign key (`user_id`) references `user`(`id`) on delete set null
+);
+
+alter table `task` add `pipeline_run_id` int null references `project__pipeline_run`(`id`) on delete set null;
+alter table `task` add `pipeline_stage` varchar(255) not null default '';
@@ -0,0 +1,2 @@
+alter table `project__template` add `require_approval` boolean not null default false;
+alter table `project__template` add `approvers` text;
@@ -0,0 +1 @@
+alter table `project__template` add `version_strategy` varchar(20) not null default '';
@@ -0,0 +1,2 @@
+alter table `project__template` add `concurrency_groups` text;
+alter table `project__template` add `lock_inventory` boolean not null default false;
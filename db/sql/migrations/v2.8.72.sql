@@ -0,0 +1 @@
+alter table `project__template` add `concurrency_mode` varchar(20) not null default '';
@@ -0,0 +1,15 @@
+alter table `task` add `diff` boolean not null default false;
+
+create table `task__diff` (
+	`id` integer primary key autoincrement,
+	`task_id` int not null,
+	`host` varchar(255) not null,
+	`task_name` varchar(1000) not null,
+	`before_header` varchar(1000) not null default '',
+	`before` longtext,
+	`after_header` varchar(1000) not null default '',
+	`after` longtext,
+	`prepared` longtext,
+
+	foreign key (`task_id`) references task(`id`) on delete cascade
+);
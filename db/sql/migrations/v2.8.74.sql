@@ -0,0 +1,15 @@
+alter table `project__template` add `syntax_check` boolean not null default false;
+alter table `project__template` add `lint_command` varchar(1000) not null default '';
+alter table `task` add `validate_only` boolean not null default false;
+
+create table `task__annotation` (
+	`id` integer primary key autoincrement,
+	`task_id` int not null,
+	`source` varchar(20) not null,
+	`file` varchar(1000) not null default '',
+	`line` int not null default 0,
+	`rule` varchar(255) not null default '',
+	`message` text,
+
+	foreign key (`task_id`) references task(`id`) on delete cascade
+);
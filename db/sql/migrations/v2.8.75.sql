@@ -0,0 +1 @@
+alter table `project__template` add `app` varchar(50) not null default '';
@@ -0,0 +1,149 @@
+package sql
+
+import (
+	"github.com/ansible-semaphore/semaphore/db"
+	"github.com/masterminds/squirrel"
+	"time"
+)
+
+func (d *SqlDb) GetPipelines(projectID int) (pipelines []db.Pipeline, err error) {
+	err = d.getObjects(projectID, db.PipelineProps, db.RetrieveQueryParams{}, &pipelines)
+	if err != nil {
+		return
+	}
+	err = db.FillPipelines(pipelines)
+	return
+}
+
+func (d *SqlDb) GetPipeline(projectID int, pipelineID int) (pipeline db.Pipeline, err error) {
+	err = d.getObject(projectID, db.PipelineProps, pipelineID, &pipeline)
+	if err != nil {
+		return
+	}
+	err = db.FillPipeline(&pipeline)
+	return
+}
+
+func (d *SqlDb) CreatePipeline(pipeline db.Pipeline) (newPipeline db.Pipeline, err error) {
+	insertID, err := d.insert(
+		"id",
+		"insert into project__pipeline (project_id, name, description, stages) values (?, ?, ?, ?)",
+		pipeline.ProjectID,
+		pipeline.Name,
+		pipeline.Description,
+		db.ObjectToJSON(pipeline.Stages))
+
+	if err != nil {
+		return
+	}
+
+	newPipeline = pipeline
+	newPipeline.ID = insertID
+	return
+}
+
+func (d *SqlDb) UpdatePipeline(pipeline db.Pipeline) error {
+	_, err := d.exec(
+		"update project__pipeline set name=?, description=?, stages=? where project_id=? and id=?",
+		pipeline.Name,
+		pipeline.Description,
+		db.ObjectToJSON(pipeline.Stages),
+		pipeline.ProjectID,
+		pipeline.ID)
+
+	return err
+}
+
+func (d *SqlDb) DeletePipeline(projectID int, pipelineID int) error {
+	_, err := d.exec(
+		"delete from project__pipeline_run where project_id=? and pipeline_id=?",
+		projectID,
+		pipelineID)
+
+	if err != nil {
+		return err
+	}
+
+	return d.deleteObject(projectID, db.PipelineProps, pipelineID)
+}
+
+func (d *SqlDb) CreatePipelineRun(run db.PipelineRun) (newRun db.PipelineRun, err error) {
+	run.Created = time.Now()
+
+	insertID, err := d.insert(
+		"id",
+		"insert into project__pipeline_run (project_id, pipeline_id, user_id, status, environment, created) "+
+			"values (?, ?, ?, ?, ?, ?)",
+		run.ProjectID,
+		run.PipelineID,
+		run.UserID,
+		run.Status,
+		run.Environment,
+		run.Created)
+
+	if err != nil {
+		return
+	}
+
+	newRun = run
+	newRun.ID = insertID
+	return
+}
+
+func (d *SqlDb) UpdatePipelineRun(run db.PipelineRun) error {
+	_, err := d.exec(
+		"update project__pipeline_run set status=?, `end`=? where project_id=? and id=?",
+		run.Status,
+		run.End,
+		run.ProjectID,
+		run.ID)
+
+	return err
+}
+
+func (d *SqlDb) GetPipelineRun(projectID int, runID int) (run db.PipelineRun, err error) {
+	err = d.getObject(projectID, db.PipelineRunProps, runID, &run)
+	return
+}
+
+func (d *SqlDb) GetPipelineRuns(projectID int, pipelineID int, params db.RetrieveQueryParams) (runs []db.PipelineRun, err error) {
+	q := squirrel.Select("*").
+		From("project__pipeline_run").
+		Where("project_id=? and pipeline_id=?", projectID, pipelineID).
+		OrderBy("created desc, id desc")
+
+	if params.Count > 0 {
+		q = q.Limit(uint64(params.Count)).Offset(uint64(params.Offset))
+	}
+
+	query, args, err := q.ToSql()
+
+	if err != nil {
+		return
+	}
+
+	_, err = d.selectAll(&runs, query, args...)
+	return
+}
+
+func (d *SqlDb) GetPipelineRunTasks(projectID int, runID int) (tasks []db.Task, err error) {
+	q := squirrel.Select("*").
+		From("task").
+		Where("project_id=? and pipeline_run_id=?", projectID, runID).
+		OrderBy("created asc, id asc")
+
+	query, args, err := q.ToSql()
+
+	if err != nil {
+		return
+	}
+
+	_, err = d.selectAll(&tasks, query, args...)
+
+	if err != nil {
+		return
+	}
+
+	err = db.FillTasks(tasks)
+	return
+}
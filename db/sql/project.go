@@ -37,6 +37,22 @@ func (d *SqlDb) GetProjects(userID int) (projects []db.Project, err error) {
 
 	_, err = d.selectAll(&projects, query, args...)
 
+	if err != nil {
+		return
+	}
+
+	err = db.FillProjects(projects)
+	return
+}
+
+func (d *SqlDb) GetAllProjects() (projects []db.Project, err error) {
+	_, err = d.selectAll(&projects, "select * from project order by name")
+
+	if err != nil {
+		return
+	}
+
+	err = db.FillProjects(projects)
 	return
 }
 
@@ -52,6 +68,11 @@ func (d *SqlDb) GetProject(projectID int) (project db.Project, err error) {
 
 	err = d.selectOne(&project, query, args...)
 
+	if err != nil {
+		return
+	}
+
+	err = db.FillProject(&project)
 	return
 }
 
@@ -63,6 +84,8 @@ func (d *SqlDb) DeleteProject(projectID int) error {
 	}
 
 	statements := []string{
+		"delete from project__pipeline_run where project_id=?",
+		"delete from project__pipeline where project_id=?",
 		"delete from project__template where project_id=?",
 		"delete from project__user where project_id=?",
 		"delete from project__repository where project_id=?",
@@ -85,11 +108,15 @@ func (d *SqlDb) DeleteProject(projectID int) error {
 
 func (d *SqlDb) UpdateProject(project db.Project) error {
 	_, err := d.exec(
-		"update project set name=?, alert=?, alert_chat=?, max_parallel_tasks=? where id=?",
+		"update project set name=?, alert=?, alert_chat=?, max_parallel_tasks=?, "+
+			"max_tasks_per_template=?, output_retention_days=?, log_mask_patterns=? where id=?",
 		project.Name,
 		project.Alert,
 		project.AlertChat,
 		project.MaxParallelTasks,
+		project.MaxTasksPerTemplate,
+		project.OutputRetentionDays,
+		db.ObjectToJSON(project.LogMaskPatterns),
 		project.ID)
 	return err
 }
@@ -0,0 +1,40 @@
+package sql
+
+import (
+	"database/sql"
+	"github.com/ansible-semaphore/semaphore/db"
+)
+
+func (d *SqlDb) GetRunner(runnerID int) (runner db.Runner, err error) {
+	err = d.selectOne(&runner, "select * from runner where id=?", runnerID)
+
+	if err == sql.ErrNoRows {
+		err = db.ErrNotFound
+	}
+
+	return
+}
+
+func (d *SqlDb) GetRunners() (runners []db.Runner, err error) {
+	_, err = d.selectAll(&runners, "select * from runner order by id")
+	return
+}
+
+func (d *SqlDb) CreateRunner(runner db.Runner) (newRunner db.Runner, err error) {
+	insertID, err := d.insert(
+		"id",
+		"insert into runner (token) values (?)",
+		runner.Token)
+
+	if err != nil {
+		return
+	}
+
+	newRunner = runner
+	newRunner.ID = insertID
+	return
+}
+
+func (d *SqlDb) DeleteRunner(runnerID int) error {
+	return validateMutationResult(d.exec("delete from runner where id=?", runnerID))
+}
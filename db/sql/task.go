@@ -4,19 +4,24 @@ import (
 	"database/sql"
 	"github.com/ansible-semaphore/semaphore/db"
 	"github.com/masterminds/squirrel"
+	"time"
 )
 
 func (d *SqlDb) CreateTask(task db.Task) (db.Task, error) {
+	task.SurveyValuesJSON = db.ObjectToJSON(task.SurveyValues)
 	err := d.sql.Insert(&task)
 	return task, err
 }
 
 func (d *SqlDb) UpdateTask(task db.Task) error {
 	_, err := d.exec(
-		"update task set status=?, start=?, `end`=? where id=?",
+		"update task set status=?, start=?, `end`=?, version=?, commit_hash=?, commit_message=? where id=?",
 		task.Status,
 		task.Start,
 		task.End,
+		task.Version,
+		task.CommitHash,
+		task.CommitMessage,
 		task.ID)
 
 	return err
@@ -31,7 +36,205 @@ func (d *SqlDb) CreateTaskOutput(output db.TaskOutput) (db.TaskOutput, error) {
 	return output, err
 }
 
-func (d *SqlDb) getTasks(projectID int, templateID *int, params db.RetrieveQueryParams, tasks *[]db.TaskWithTpl) (err error) {
+// taskOutputsPerInsert limits number of rows in one insert statement
+// to keep number of query parameters within database limits.
+const taskOutputsPerInsert = 300
+
+func (d *SqlDb) CreateTaskOutputs(outputs []db.TaskOutput) error {
+	for start := 0; start < len(outputs); start += taskOutputsPerInsert {
+		end := start + taskOutputsPerInsert
+		if end > len(outputs) {
+			end = len(outputs)
+		}
+
+		q := squirrel.Insert("task__output").Columns("task_id", "task", "output", "time")
+
+		for _, output := range outputs[start:end] {
+			q = q.Values(output.TaskID, "", output.Output, output.Time)
+		}
+
+		query, args, err := q.ToSql()
+		if err != nil {
+			return err
+		}
+
+		_, err = d.exec(query, args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *SqlDb) CreateTaskHosts(taskID int, hosts []db.TaskHost) error {
+	for _, host := range hosts {
+		_, err := d.exec(
+			"insert into task__host (task_id, host, ok, changed, failed, unreachable, skipped, failed_tasks) "+
+				"values (?, ?, ?, ?, ?, ?, ?, ?)",
+			taskID,
+			host.Host,
+			host.Ok,
+			host.Changed,
+			host.Failed,
+			host.Unreachable,
+			host.Skipped,
+			db.ObjectToJSON(host.FailedTasks))
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *SqlDb) GetTaskHosts(projectID int, taskID int) (hosts []db.TaskHost, err error) {
+	// check if task exists in the project
+	_, err = d.GetTask(projectID, taskID)
+
+	if err != nil {
+		return
+	}
+
+	_, err = d.selectAll(&hosts,
+		"select * from task__host where task_id=? order by host asc",
+		taskID)
+
+	if err != nil {
+		return
+	}
+
+	err = db.FillTaskHosts(hosts)
+	return
+}
+
+func (d *SqlDb) CreateTaskDiffs(taskID int, diffs []db.TaskDiff) error {
+	for _, diff := range diffs {
+		_, err := d.exec(
+			"insert into task__diff (task_id, host, task_name, before_header, `before`, after_header, `after`, prepared) "+
+				"values (?, ?, ?, ?, ?, ?, ?, ?)",
+			taskID,
+			diff.Host,
+			diff.TaskName,
+			diff.BeforeHeader,
+			diff.Before,
+			diff.AfterHeader,
+			diff.After,
+			diff.Prepared)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *SqlDb) GetTaskDiffs(projectID int, taskID int) (diffs []db.TaskDiff, err error) {
+	// check if task exists in the project
+	_, err = d.GetTask(projectID, taskID)
+
+	if err != nil {
+		return
+	}
+
+	_, err = d.selectAll(&diffs,
+		"select * from task__diff where task_id=? order by id asc",
+		taskID)
+
+	return
+}
+
+func (d *SqlDb) CreateTaskAnnotations(taskID int, annotations []db.TaskAnnotation) error {
+	for _, annotation := range annotations {
+		_, err := d.exec(
+			"insert into task__annotation (task_id, source, file, line, rule, message) values (?, ?, ?, ?, ?, ?)",
+			taskID,
+			annotation.Source,
+			annotation.File,
+			annotation.Line,
+			annotation.Rule,
+			annotation.Message)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *SqlDb) GetTaskAnnotations(projectID int, taskID int) (annotations []db.TaskAnnotation, err error) {
+	// check if task exists in the project
+	_, err = d.GetTask(projectID, taskID)
+
+	if err != nil {
+		return
+	}
+
+	_, err = d.selectAll(&annotations,
+		"select * from task__annotation where task_id=? order by id asc",
+		taskID)
+
+	return
+}
+
+func (d *SqlDb) CreateTaskArtifact(artifact db.TaskArtifact) (newArtifact db.TaskArtifact, err error) {
+	artifact.Created = time.Now()
+
+	insertID, err := d.insert(
+		"id",
+		"insert into task__artifact (task_id, name, size, created) values (?, ?, ?, ?)",
+		artifact.TaskID,
+		artifact.Name,
+		artifact.Size,
+		artifact.Created)
+
+	if err != nil {
+		return
+	}
+
+	newArtifact = artifact
+	newArtifact.ID = insertID
+	return
+}
+
+func (d *SqlDb) GetTaskArtifacts(projectID int, taskID int) (artifacts []db.TaskArtifact, err error) {
+	// check if task exists in the project
+	_, err = d.GetTask(projectID, taskID)
+
+	if err != nil {
+		return
+	}
+
+	_, err = d.selectAll(&artifacts,
+		"select * from task__artifact where task_id=? order by name asc",
+		taskID)
+	return
+}
+
+func (d *SqlDb) GetTaskArtifact(projectID int, taskID int, artifactID int) (artifact db.TaskArtifact, err error) {
+	// check if task exists in the project
+	_, err = d.GetTask(projectID, taskID)
+
+	if err != nil {
+		return
+	}
+
+	err = d.selectOne(&artifact,
+		"select * from task__artifact where task_id=? and id=?",
+		taskID,
+		artifactID)
+
+	if err == sql.ErrNoRows {
+		err = db.ErrNotFound
+	}
+
+	return
+}
+
+func (d *SqlDb) getTasks(projectID int, templateID *int, status *db.TaskStatus, excludeValidations bool, params db.RetrieveQueryParams, tasks *[]db.TaskWithTpl) (err error) {
 	fields := "task.*"
 	fields += ", tpl.playbook as tpl_playbook" +
 		", `user`.name as user_name" +
@@ -50,6 +253,14 @@ func (d *SqlDb) getTasks(projectID int, templateID *int, params db.RetrieveQuery
 		q = q.Where("tpl.project_id=? AND task.template_id=?", projectID, templateID)
 	}
 
+	if status != nil {
+		q = q.Where("task.status=?", *status)
+	}
+
+	if excludeValidations {
+		q = q.Where("task.validate_only=?", false)
+	}
+
 	if params.Count > 0 {
 		q = q.Limit(uint64(params.Count))
 	}
@@ -91,16 +302,56 @@ func (d *SqlDb) GetTask(projectID int, taskID int) (task db.Task, err error) {
 		return
 	}
 
+	err = db.FillTask(&task)
+	return
+}
+
+func (d *SqlDb) GetTasksByStatus(statuses []db.TaskStatus) (tasks []db.Task, err error) {
+	tasks = make([]db.Task, 0)
+
+	if len(statuses) == 0 {
+		return
+	}
+
+	q := squirrel.Select("*").
+		From("task").
+		Where(squirrel.Eq{"status": statuses}).
+		OrderBy("created asc, id asc")
+
+	query, args, err := q.ToSql()
+
+	if err != nil {
+		return
+	}
+
+	_, err = d.selectAll(&tasks, query, args...)
+
+	if err != nil {
+		return
+	}
+
+	err = db.FillTasks(tasks)
 	return
 }
 
 func (d *SqlDb) GetTemplateTasks(projectID int, templateID int, params db.RetrieveQueryParams) (tasks []db.TaskWithTpl, err error) {
-	err = d.getTasks(projectID, &templateID, params, &tasks)
+	err = d.getTasks(projectID, &templateID, nil, false, params, &tasks)
+	return
+}
+
+func (d *SqlDb) GetTemplateTasksByStatus(projectID int, templateID int, status db.TaskStatus, params db.RetrieveQueryParams) (tasks []db.TaskWithTpl, err error) {
+	err = d.getTasks(projectID, &templateID, &status, false, params, &tasks)
+	return
+}
+
+func (d *SqlDb) GetTemplateBuilds(projectID int, templateID int, params db.RetrieveQueryParams) (tasks []db.TaskWithTpl, err error) {
+	status := db.TaskSuccessStatus
+	err = d.getTasks(projectID, &templateID, &status, true, params, &tasks)
 	return
 }
 
 func (d *SqlDb) GetProjectTasks(projectID int, params db.RetrieveQueryParams) (tasks []db.TaskWithTpl, err error) {
-	err = d.getTasks(projectID, nil, params, &tasks)
+	err = d.getTasks(projectID, nil, nil, false, params, &tasks)
 	return
 }
 
@@ -118,6 +369,36 @@ func (d *SqlDb) DeleteTaskWithOutputs(projectID int, taskID int) (err error) {
 		return
 	}
 
+	_, err = d.exec("delete from task__host where task_id=?", taskID)
+
+	if err != nil {
+		return
+	}
+
+	_, err = d.exec("delete from task__output_archive where task_id=?", taskID)
+
+	if err != nil {
+		return
+	}
+
+	_, err = d.exec("delete from task__artifact where task_id=?", taskID)
+
+	if err != nil {
+		return
+	}
+
+	_, err = d.exec("delete from task__diff where task_id=?", taskID)
+
+	if err != nil {
+		return
+	}
+
+	_, err = d.exec("delete from task__annotation where task_id=?", taskID)
+
+	if err != nil {
+		return
+	}
+
 	_, err = d.exec("delete from task where id=?", taskID)
 	return
 }
@@ -130,8 +411,114 @@ func (d *SqlDb) GetTaskOutputs(projectID int, taskID int) (output []db.TaskOutpu
 		return
 	}
 
-	_, err = d.selectAll(&output,
+	var archive db.TaskOutputArchive
+
+	err = d.selectOne(&archive, "select * from task__output_archive where task_id=?", taskID)
+
+	switch err {
+	case nil:
+		output, err = archive.GetOutputs()
+		if err != nil {
+			return
+		}
+	case sql.ErrNoRows:
+		err = nil
+	default:
+		return
+	}
+
+	var records []db.TaskOutput
+
+	_, err = d.selectAll(&records,
 		"select task_id, task, time, output from task__output where task_id=? order by time asc",
 		taskID)
+
+	output = append(output, records...)
 	return
 }
+
+func (d *SqlDb) DeleteTaskOutputsBefore(projectID int, before time.Time) error {
+	_, err := d.exec(
+		"delete from task__output_archive where task_id in "+
+			"(select id from task where project_id=? and `end` < ?)",
+		projectID,
+		before)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = d.exec(
+		"delete from task__output where task_id in "+
+			"(select id from task where project_id=? and `end` < ?)",
+		projectID,
+		before)
+
+	return err
+}
+
+func (d *SqlDb) CompressTaskOutputsBefore(projectID int, before time.Time) error {
+	var taskIDs []int
+
+	_, err := d.selectAll(&taskIDs,
+		"select distinct o.task_id from task__output as o "+
+			"join task as t on t.id=o.task_id "+
+			"where t.project_id=? and t.`end` < ?",
+		projectID,
+		before)
+
+	if err != nil {
+		return err
+	}
+
+	for _, taskID := range taskIDs {
+		err = d.compressTaskOutputs(projectID, taskID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// compressTaskOutputs moves all output records of the task to the archive.
+// The output already stored in the archive is preserved.
+func (d *SqlDb) compressTaskOutputs(projectID int, taskID int) error {
+	outputs, err := d.GetTaskOutputs(projectID, taskID)
+
+	if err != nil {
+		return err
+	}
+
+	archive, err := db.CreateTaskOutputArchive(taskID, outputs)
+
+	if err != nil {
+		return err
+	}
+
+	tx, err := d.sql.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{"delete from task__output_archive where task_id=?", []interface{}{taskID}},
+		{"insert into task__output_archive (task_id, output) values (?, ?)", []interface{}{taskID, archive.Output}},
+		{"delete from task__output where task_id=?", []interface{}{taskID}},
+	}
+
+	for _, statement := range statements {
+		_, err = tx.Exec(d.PrepareQuery(statement.query), statement.args...)
+
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,136 @@
+package sql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ansible-semaphore/semaphore/db"
+	"github.com/go-gorp/gorp/v3"
+)
+
+// recordedExec is a statement executed through recordingDriver.
+type recordedExec struct {
+	query string
+	args  []driver.Value
+}
+
+// recordingDriver is a database driver which records executed statements,
+// so queries can be checked without a database server.
+type recordingDriver struct {
+	lock  sync.Mutex
+	execs []recordedExec
+}
+
+func (d *recordingDriver) Open(name string) (driver.Conn, error) {
+	return &recordingConn{driver: d}, nil
+}
+
+type recordingConn struct {
+	driver *recordingDriver
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{conn: c, query: query}, nil
+}
+
+func (c *recordingConn) Close() error {
+	return nil
+}
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	return nil, driver.ErrSkip
+}
+
+type recordingStmt struct {
+	conn  *recordingConn
+	query string
+}
+
+func (s *recordingStmt) Close() error {
+	return nil
+}
+
+func (s *recordingStmt) NumInput() int {
+	return -1
+}
+
+func (s *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.conn.driver.lock.Lock()
+	defer s.conn.driver.lock.Unlock()
+	s.conn.driver.execs = append(s.conn.driver.execs, recordedExec{query: s.query, args: args})
+	return driver.RowsAffected(1), nil
+}
+
+func (s *recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, driver.ErrSkip
+}
+
+var testDriver = &recordingDriver{}
+
+func init() {
+	sql.Register("semaphore_recording", testDriver)
+}
+
+func createRecordingDb(t *testing.T, dialect gorp.Dialect) *SqlDb {
+	conn, err := sql.Open("semaphore_recording", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testDriver.lock.Lock()
+	testDriver.execs = nil
+	testDriver.lock.Unlock()
+
+	return &SqlDb{sql: &gorp.DbMap{Db: conn, Dialect: dialect}}
+}
+
+func TestUpdateTaskStoresVersionAndCommit(t *testing.T) {
+	dialects := []gorp.Dialect{
+		gorp.MySQLDialect{Engine: "InnoDB", Encoding: "UTF8"},
+		gorp.PostgresDialect{},
+	}
+
+	for _, dialect := range dialects {
+		d := createRecordingDb(t, dialect)
+
+		version := "1.2.0"
+		hash := "3a1f9c2"
+
+		err := d.UpdateTask(db.Task{
+			ID:            5,
+			Status:        db.TaskSuccessStatus,
+			Version:       &version,
+			CommitHash:    &hash,
+			CommitMessage: "Release 1.2.0",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(testDriver.execs) != 1 {
+			t.Fatal("task must be updated by one statement", len(testDriver.execs))
+		}
+
+		exec := testDriver.execs[0]
+
+		for _, column := range []string{"version", "commit_hash", "commit_message"} {
+			if !strings.Contains(exec.query, column+"=") {
+				t.Fatal("column "+column+" must be updated:", exec.query)
+			}
+		}
+
+		values := make(map[interface{}]bool)
+		for _, arg := range exec.args {
+			values[arg] = true
+		}
+
+		for _, value := range []string{version, hash, "Release 1.2.0"} {
+			if !values[value] {
+				t.Fatal("value " + value + " must be passed to the query")
+			}
+		}
+	}
+}
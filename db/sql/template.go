@@ -17,8 +17,10 @@ func (d *SqlDb) CreateTemplate(template db.Template) (newTemplate db.Template, e
 		"id",
 		"insert into project__template (project_id, inventory_id, repository_id, environment_id, "+
 			"name, playbook, arguments, allow_override_args_in_task, description, vault_key_id, `type`, start_version,"+
-			"build_template_id, view_id, autorun, survey_vars, suppress_success_alerts)"+
-			"values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			"build_template_id, view_id, autorun, survey_vars, suppress_success_alerts, timeout, "+
+			"retry_count, retry_delay, retry_on, priority, container_image, artifacts, require_approval, approvers, version_strategy, "+
+			"concurrency_groups, lock_inventory, concurrency_mode, syntax_check, lint_command, app)"+
+			"values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
 		template.ProjectID,
 		template.InventoryID,
 		template.RepositoryID,
@@ -35,7 +37,23 @@ func (d *SqlDb) CreateTemplate(template db.Template) (newTemplate db.Template, e
 		template.ViewID,
 		template.Autorun,
 		db.ObjectToJSON(template.SurveyVars),
-		template.SuppressSuccessAlerts)
+		template.SuppressSuccessAlerts,
+		template.Timeout,
+		template.RetryCount,
+		template.RetryDelay,
+		template.RetryOn,
+		template.Priority,
+		template.ContainerImage,
+		template.Artifacts,
+		template.RequireApproval,
+		db.ObjectToJSON(template.Approvers),
+		template.VersionStrategy,
+		db.ObjectToJSON(template.ConcurrencyGroups),
+		template.LockInventory,
+		template.ConcurrencyMode,
+		template.SyntaxCheck,
+		template.LintCommand,
+		template.App)
 
 	if err != nil {
 		return
@@ -76,7 +94,23 @@ func (d *SqlDb) UpdateTemplate(template db.Template) error {
 		"view_id=?, "+
 		"autorun=?, "+
 		"survey_vars=?, "+
-		"suppress_success_alerts=? "+
+		"suppress_success_alerts=?, "+
+		"timeout=?, "+
+		"retry_count=?, "+
+		"retry_delay=?, "+
+		"retry_on=?, "+
+		"priority=?, "+
+		"container_image=?, "+
+		"artifacts=?, "+
+		"require_approval=?, "+
+		"approvers=?, "+
+		"version_strategy=?, "+
+		"concurrency_groups=?, "+
+		"lock_inventory=?, "+
+		"concurrency_mode=?, "+
+		"syntax_check=?, "+
+		"lint_command=?, "+
+		"app=? "+
 		"where id=? and project_id=?",
 		template.InventoryID,
 		template.RepositoryID,
@@ -94,6 +128,22 @@ func (d *SqlDb) UpdateTemplate(template db.Template) error {
 		template.Autorun,
 		db.ObjectToJSON(template.SurveyVars),
 		template.SuppressSuccessAlerts,
+		template.Timeout,
+		template.RetryCount,
+		template.RetryDelay,
+		template.RetryOn,
+		template.Priority,
+		template.ContainerImage,
+		template.Artifacts,
+		template.RequireApproval,
+		db.ObjectToJSON(template.Approvers),
+		template.VersionStrategy,
+		db.ObjectToJSON(template.ConcurrencyGroups),
+		template.LockInventory,
+		template.ConcurrencyMode,
+		template.SyntaxCheck,
+		template.LintCommand,
+		template.App,
 		template.ID,
 		template.ProjectID,
 	)
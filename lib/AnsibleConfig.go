@@ -0,0 +1,112 @@
+package lib
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ansibleConfigPathKeys contains settings of ansible.cfg which are lists of paths.
+var ansibleConfigPathKeys = []string{"callback_plugins", "roles_path", "collections_path", "collections_paths"}
+
+// AnsibleSettings contains settings which ansible uses for a task.
+// Environment variables take precedence over ansible.cfg like in ansible itself.
+type AnsibleSettings struct {
+	// Env contains environment variables of the task.
+	Env map[string]string
+
+	// Config contains settings of the [defaults] section of ansible.cfg.
+	Config map[string]string
+}
+
+// ReadAnsibleSettings reads ansible.cfg which ansible uses in the directory dir:
+// the file set by ANSIBLE_CONFIG of env or ansible.cfg of the directory.
+// Relative paths of ansible.cfg are resolved against the directory of the file,
+// because they are passed to ansible by environment variables.
+func ReadAnsibleSettings(dir string, env map[string]string) (AnsibleSettings, error) {
+	settings := AnsibleSettings{
+		Env:    env,
+		Config: make(map[string]string),
+	}
+
+	configPath := filepath.Join(dir, "ansible.cfg")
+	if p, ok := env["ANSIBLE_CONFIG"]; ok && p != "" {
+		configPath = p
+		if !filepath.IsAbs(configPath) {
+			configPath = filepath.Join(dir, configPath)
+		}
+	}
+
+	file, err := os.Open(configPath)
+	if os.IsNotExist(err) {
+		return settings, nil
+	}
+	if err != nil {
+		return settings, err
+	}
+	defer file.Close() //nolint: errcheck
+
+	section := ""
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+
+		if section != "defaults" {
+			continue
+		}
+
+		i := strings.IndexAny(line, "=:")
+		if i < 0 {
+			continue
+		}
+
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		settings.Config[key] = strings.TrimSpace(line[i+1:])
+	}
+
+	if err = scanner.Err(); err != nil {
+		return settings, err
+	}
+
+	configDir := filepath.Dir(configPath)
+
+	for _, key := range ansibleConfigPathKeys {
+		value, ok := settings.Config[key]
+		if !ok {
+			continue
+		}
+
+		paths := strings.Split(value, ":")
+		for i, p := range paths {
+			if p != "" && !filepath.IsAbs(p) && !strings.HasPrefix(p, "~") && !strings.HasPrefix(p, "$") {
+				paths[i] = filepath.Join(configDir, p)
+			}
+		}
+
+		settings.Config[key] = strings.Join(paths, ":")
+	}
+
+	return settings, nil
+}
+
+// Get returns the value of the setting which can be set by the environment variable envName
+// or by key of ansible.cfg.
+func (s AnsibleSettings) Get(envName string, key string) (string, bool) {
+	if value, ok := s.Env[envName]; ok {
+		return value, true
+	}
+
+	value, ok := s.Config[key]
+	return value, ok
+}
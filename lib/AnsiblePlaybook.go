@@ -1,52 +1,133 @@
 package lib
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/ansible-semaphore/semaphore/db"
-	"github.com/ansible-semaphore/semaphore/util"
-	"os"
 	"os/exec"
 	"strings"
 )
 
+// ansiblePlaybookUnreachableExitCode is the exit code of ansible-playbook
+// when one or more hosts were unreachable.
+const ansiblePlaybookUnreachableExitCode = 4
+
+// ErrUnreachableHosts is reported when a playbook failed because some hosts were unreachable.
+var ErrUnreachableHosts = errors.New("one or more hosts were unreachable")
+
+// IsUnreachableHostsError checks if the playbook failed because some hosts were unreachable.
+func IsUnreachableHostsError(err error) bool {
+	if errors.Is(err, ErrUnreachableHosts) {
+		return true
+	}
+
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr) && exitErr.ExitCode() == ansiblePlaybookUnreachableExitCode
+}
+
 type AnsiblePlaybook struct {
-	TemplateID int
+	TaskID     int
 	Repository db.Repository
 	Logger     Logger
+
+	// Executor runs the commands. LocalExecutor is used if it is nil.
+	Executor Executor
+
+	// Context stops running commands when it is cancelled.
+	Context context.Context
 }
 
 func (p AnsiblePlaybook) makeCmd(command string, args []string, environmentVars *[]string) *exec.Cmd {
-	cmd := exec.Command(command, args...) //nolint: gas
-	cmd.Dir = p.GetFullPath()
-
-	cmd.Env = os.Environ()
-	cmd.Env = append(cmd.Env, fmt.Sprintf("HOME=%s", util.Config.TmpPath))
-	cmd.Env = append(cmd.Env, fmt.Sprintf("PWD=%s", cmd.Dir))
-	cmd.Env = append(cmd.Env, "PYTHONUNBUFFERED=1")
-	cmd.Env = append(cmd.Env, "ANSIBLE_FORCE_COLOR=True")
+	dir := p.GetFullPath()
+
+	env := []string{
+		fmt.Sprintf("PWD=%s", dir),
+		"PYTHONUNBUFFERED=1",
+		"ANSIBLE_FORCE_COLOR=True",
+	}
 	if environmentVars != nil {
-		cmd.Env = append(cmd.Env, *environmentVars...)
+		env = append(env, *environmentVars...)
+	}
+
+	executor := p.Executor
+	if executor == nil {
+		executor = LocalExecutor{}
 	}
 
-	return cmd
+	return executor.Command(command, args, dir, env)
+}
+
+// runCommand runs the command and stops it if the context is cancelled.
+func (p AnsiblePlaybook) runCommand(cmd *exec.Cmd) error {
+	err := runCommand(p.Context, cmd)
+
+	if p.Context != nil && p.Context.Err() != nil {
+		if executor, ok := p.Executor.(StoppableExecutor); ok {
+			executor.Stop()
+		}
+	}
+
+	return err
 }
 
 func (p AnsiblePlaybook) runCmd(command string, args []string) error {
 	cmd := p.makeCmd(command, args, nil)
 	p.Logger.LogCmd(cmd)
-	return cmd.Run()
+	return p.runCommand(cmd)
 }
 
-func (p AnsiblePlaybook) RunPlaybook(args []string, environmentVars *[]string, cb func(*os.Process)) error {
+func (p AnsiblePlaybook) RunPlaybook(args []string, environmentVars *[]string) error {
 	cmd := p.makeCmd("ansible-playbook", args, environmentVars)
 	p.Logger.LogCmd(cmd)
 	cmd.Stdin = strings.NewReader("")
-	err := cmd.Start()
-	if err != nil {
-		return err
+	return p.runCommand(cmd)
+}
+
+// RunScript runs the script or the command of a command template.
+func (p AnsiblePlaybook) RunScript(command string, args []string, environmentVars *[]string) error {
+	cmd := p.makeCmd(command, args, environmentVars)
+	p.Logger.LogCmd(cmd)
+	cmd.Stdin = strings.NewReader("")
+	return p.runCommand(cmd)
+}
+
+// RunCheck runs the command in the repository and returns its combined output.
+// The output is logged after the command finishes.
+func (p AnsiblePlaybook) RunCheck(command string, args []string, environmentVars *[]string) (string, error) {
+	cmd := p.makeCmd(command, args, environmentVars)
+	cmd.Stdin = strings.NewReader("")
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := p.runCommand(cmd)
+
+	for _, line := range strings.Split(strings.TrimRight(output.String(), "\n"), "\n") {
+		if line != "" {
+			p.Logger.Log(line)
+		}
 	}
-	cb(cmd.Process)
-	return cmd.Wait()
+
+	return output.String(), err
+}
+
+// GetGalaxyVersion returns the first line of the output of ansible-galaxy --version,
+// which contains the version of ansible used by the executor.
+func (p AnsiblePlaybook) GetGalaxyVersion() (string, error) {
+	cmd := p.makeCmd("ansible-galaxy", []string{"--version"}, nil)
+	cmd.Stdin = strings.NewReader("")
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+
+	if err := p.runCommand(cmd); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(strings.SplitN(output.String(), "\n", 2)[0]), nil
 }
 
 func (p AnsiblePlaybook) RunGalaxy(args []string) error {
@@ -54,6 +135,6 @@ func (p AnsiblePlaybook) RunGalaxy(args []string) error {
 }
 
 func (p AnsiblePlaybook) GetFullPath() (path string) {
-	path = p.Repository.GetFullPath(p.TemplateID)
+	path = p.Repository.GetWorkspacePath(p.TaskID)
 	return
 }
@@ -0,0 +1,80 @@
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gobuffalo/packr"
+)
+
+var ansiblePlugins = packr.NewBox("./ansible_plugins")
+
+// CallbackPlugins contains names of the bundled callback plugins.
+var CallbackPlugins = []string{"semaphore_hosts", "semaphore_diffs"}
+
+// InstallCallbackPlugins writes the bundled callback plugins to dir.
+func InstallCallbackPlugins(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for _, name := range CallbackPlugins {
+		content, err := ansiblePlugins.MustBytes(name + ".py")
+		if err != nil {
+			return err
+		}
+
+		if err = ioutil.WriteFile(path.Join(dir, name+".py"), content, 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetCallbackPluginsEnv returns environment variables which enable the callback plugins installed to dir.
+// The plugins are added to callbacks configured by the environment or ansible.cfg of the project.
+func GetCallbackPluginsEnv(dir string, settings AnsibleSettings) []string {
+	plugins, _ := settings.Get("ANSIBLE_CALLBACK_PLUGINS", "callback_plugins")
+
+	// callback_whitelist is the old name of callbacks_enabled
+	enabled, _ := settings.Get("ANSIBLE_CALLBACKS_ENABLED", "callbacks_enabled")
+	whitelist, _ := settings.Get("ANSIBLE_CALLBACK_WHITELIST", "callback_whitelist")
+
+	enabled = appendToList(enabled, ",", strings.Split(whitelist, ",")...)
+	enabled = appendToList(enabled, ",", CallbackPlugins...)
+
+	return []string{
+		"ANSIBLE_CALLBACK_PLUGINS=" + appendToList(plugins, ":", dir),
+		"ANSIBLE_CALLBACKS_ENABLED=" + enabled,
+		"ANSIBLE_CALLBACK_WHITELIST=" + enabled,
+	}
+}
+
+// appendToList appends items which are missing in the list separated by sep.
+func appendToList(list string, sep string, items ...string) string {
+	var res []string
+
+	for _, item := range append(strings.Split(list, sep), items...) {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		found := false
+		for _, r := range res {
+			if r == item {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			res = append(res, item)
+		}
+	}
+
+	return strings.Join(res, sep)
+}
@@ -0,0 +1,48 @@
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestGetCallbackPluginsEnv(t *testing.T) {
+	repoPath, err := ioutil.TempDir("", "semaphore_ansible_cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(repoPath) //nolint: errcheck
+
+	cfg := "[defaults]\ncallback_plugins = plugins/callback\ncallbacks_enabled = profile_tasks, timer\n\n[ssh_connection]\npipelining = True\n"
+	if err = ioutil.WriteFile(path.Join(repoPath, "ansible.cfg"), []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	settings, err := ReadAnsibleSettings(repoPath, map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := strings.Join(GetCallbackPluginsEnv("/tmp/callbacks_1", settings), "\n")
+
+	if !strings.Contains(env, "ANSIBLE_CALLBACK_PLUGINS="+path.Join(repoPath, "plugins/callback")+":/tmp/callbacks_1\n") {
+		t.Fatal("callback plugins of ansible.cfg must be kept", env)
+	}
+
+	if !strings.Contains(env, "ANSIBLE_CALLBACKS_ENABLED=profile_tasks,timer,semaphore_hosts,semaphore_diffs\n") {
+		t.Fatal("callbacks enabled in ansible.cfg must be kept", env)
+	}
+
+	settings, err = ReadAnsibleSettings(repoPath, map[string]string{"ANSIBLE_CALLBACK_WHITELIST": "junit"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env = strings.Join(GetCallbackPluginsEnv("/tmp/callbacks_1", settings), "\n")
+
+	if !strings.Contains(env, "ANSIBLE_CALLBACK_WHITELIST=profile_tasks,timer,junit,semaphore_hosts,semaphore_diffs") {
+		t.Fatal("callbacks enabled in the environment must be kept", env)
+	}
+}
@@ -0,0 +1,116 @@
+package lib
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ansible-semaphore/semaphore/util"
+)
+
+// ArtifactStore keeps content of files collected from tasks.
+type ArtifactStore interface {
+	// Put stores the content of the file name of the task and returns its size.
+	Put(taskID int, name string, content io.Reader) (int64, error)
+	Get(taskID int, name string) (io.ReadCloser, error)
+	// Delete removes all files of the task.
+	Delete(taskID int) error
+}
+
+// GetArtifactStore returns the store configured for the server or runner.
+func GetArtifactStore() ArtifactStore {
+	return LocalArtifactStore{Dir: util.Config.ArtifactsPath}
+}
+
+// LocalArtifactStore keeps artifacts in the local directory Dir.
+type LocalArtifactStore struct {
+	Dir string
+}
+
+func (s LocalArtifactStore) getTaskDir(taskID int) string {
+	return filepath.Join(s.Dir, "task_"+strconv.Itoa(taskID))
+}
+
+func (s LocalArtifactStore) getPath(taskID int, name string) string {
+	// cleaning of the rooted path prevents escaping from the task directory
+	return filepath.Join(s.getTaskDir(taskID), filepath.Clean("/"+name))
+}
+
+func (s LocalArtifactStore) Put(taskID int, name string, content io.Reader) (size int64, err error) {
+	p := s.getPath(taskID, name)
+
+	err = os.MkdirAll(filepath.Dir(p), 0755)
+	if err != nil {
+		return
+	}
+
+	file, err := os.Create(p)
+	if err != nil {
+		return
+	}
+
+	size, err = io.Copy(file, content)
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return
+}
+
+func (s LocalArtifactStore) Get(taskID int, name string) (io.ReadCloser, error) {
+	return os.Open(s.getPath(taskID, name))
+}
+
+func (s LocalArtifactStore) Delete(taskID int) error {
+	return os.RemoveAll(s.getTaskDir(taskID))
+}
+
+// FindArtifacts returns paths relative to dir of the files matched by the glob pattern.
+// Directories matched by the pattern are walked recursively.
+func FindArtifacts(dir string, pattern string) (names []string, err error) {
+	matches, err := filepath.Glob(filepath.Join(dir, pattern))
+	if err != nil {
+		return
+	}
+
+	for _, match := range matches {
+		err = filepath.Walk(match, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if info.Name() == ".git" {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+
+			name, err := filepath.Rel(dir, p)
+			if err != nil {
+				return err
+			}
+
+			if name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+				return fmt.Errorf("artifact %s is outside of the repository", p)
+			}
+
+			names = append(names, name)
+			return nil
+		})
+
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,113 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/ansible-semaphore/semaphore/util"
+)
+
+// Executor makes commands which run Ansible tools like ansible-playbook and ansible-galaxy.
+type Executor interface {
+	// Command makes a command which runs name with args in the directory dir.
+	// env contains additional environment variables in the form "key=value".
+	Command(name string, args []string, dir string, env []string) *exec.Cmd
+}
+
+// StoppableExecutor is implemented by executors whose commands keep running
+// after the process of the command is killed.
+type StoppableExecutor interface {
+	// Stop stops the command which was killed.
+	Stop()
+}
+
+// LocalExecutor runs commands on the host from PATH.
+type LocalExecutor struct{}
+
+func (e LocalExecutor) Command(name string, args []string, dir string, env []string) *exec.Cmd {
+	cmd := exec.Command(name, args...) //nolint: gas
+	cmd.Dir = dir
+
+	cmd.Env = os.Environ()
+	cmd.Env = append(cmd.Env, fmt.Sprintf("HOME=%s", util.Config.TmpPath))
+	cmd.Env = append(cmd.Env, env...)
+
+	return cmd
+}
+
+// ContainerExecutor runs commands inside of a container using docker or podman CLI.
+// The working directory, HomeDir and Files are mounted to the container at the same paths.
+type ContainerExecutor struct {
+	Engine    string
+	Image     string
+	ExtraArgs []string
+
+	// Name is the name of the container, so it can be stopped when the command is killed.
+	Name string
+
+	// HomeDir is used as HOME inside of the container.
+	HomeDir string
+
+	// Files contains paths of files which the command reads, like inventory and keys.
+	Files []string
+
+	// Dirs contains paths of directories which the command can write to.
+	Dirs []string
+}
+
+func (e ContainerExecutor) Command(name string, args []string, dir string, env []string) *exec.Cmd {
+	engineArgs := []string{
+		"run",
+		"--rm",
+		"--workdir", dir,
+		"--volume", dir + ":" + dir,
+	}
+
+	if e.Name != "" {
+		engineArgs = append(engineArgs, "--name", e.Name)
+	}
+
+	if e.HomeDir != "" {
+		engineArgs = append(engineArgs,
+			"--volume", e.HomeDir+":"+e.HomeDir,
+			"--env", "HOME="+e.HomeDir)
+	}
+
+	for _, file := range e.Files {
+		engineArgs = append(engineArgs, "--volume", file+":"+file+":ro")
+	}
+
+	for _, dir := range e.Dirs {
+		engineArgs = append(engineArgs, "--volume", dir+":"+dir)
+	}
+
+	// values are passed through environment of the engine process
+	// to keep them out of the process list
+	for _, v := range env {
+		engineArgs = append(engineArgs, "--env", strings.SplitN(v, "=", 2)[0])
+	}
+
+	engineArgs = append(engineArgs, e.ExtraArgs...)
+	engineArgs = append(engineArgs, e.Image, name)
+	engineArgs = append(engineArgs, args...)
+
+	cmd := exec.Command(e.Engine, engineArgs...) //nolint: gas
+	cmd.Dir = dir
+
+	cmd.Env = os.Environ()
+	cmd.Env = append(cmd.Env, env...)
+
+	return cmd
+}
+
+// Stop removes the container, because killing of the engine CLI does not stop it.
+func (e ContainerExecutor) Stop() {
+	if e.Name == "" {
+		return
+	}
+
+	// the container does not exist anymore if it exited before the command was killed
+	_ = exec.Command(e.Engine, "rm", "--force", e.Name).Run() //nolint: gas
+}
@@ -0,0 +1,65 @@
+//go:build !windows
+
+package lib
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ansible-semaphore/semaphore/db"
+	"github.com/ansible-semaphore/semaphore/util"
+)
+
+func TestContainerExecutorStopsContainer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "semaphore_container")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir) //nolint: errcheck
+
+	oldConfig := util.Config
+	util.Config = &util.ConfigType{TmpPath: dir}
+	defer func() { util.Config = oldConfig }()
+
+	// the engine records its commands and keeps running like a container
+	calls := path.Join(dir, "calls")
+	engine := path.Join(dir, "engine")
+	script := "#!/bin/sh\necho \"$@\" >> " + calls + "\nif [ \"$1\" = run ]; then sleep 30; fi\n"
+	if err = ioutil.WriteFile(engine, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	playbook := AnsiblePlaybook{
+		Logger:     testLogger{},
+		Repository: db.Repository{GitURL: dir},
+		Executor:   ContainerExecutor{Engine: engine, Image: "ansible", Name: "semaphore-task-5"},
+		Context:    ctx,
+	}
+
+	if err = playbook.RunPlaybook([]string{"site.yml"}, nil); err != context.Canceled {
+		t.Fatal("stopped playbook must return context error", err)
+	}
+
+	content, err := ioutil.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+
+	if len(lines) != 2 || !strings.Contains(lines[0], "--name semaphore-task-5") {
+		t.Fatal("container must be named after the task", lines)
+	}
+
+	if lines[1] != "rm --force semaphore-task-5" {
+		t.Fatal("container must be removed when the command is stopped", lines)
+	}
+}
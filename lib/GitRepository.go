@@ -1,9 +1,13 @@
 package lib
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/ansible-semaphore/semaphore/db"
 	"github.com/ansible-semaphore/semaphore/util"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"strings"
@@ -13,13 +17,22 @@ type GitRepositoryDirType int
 
 const (
 	GitRepositoryTmpDir GitRepositoryDirType = iota
-	GitRepositoryRepoDir
+	GitRepositoryMirrorDir
+	GitRepositoryWorkspaceDir
 )
 
+// ErrNoTags is returned by GetLastTag if no tag is reachable from the checked out commit.
+var ErrNoTags = errors.New("no tags are reachable from the commit")
+
+// GitRepository manages a shared bare mirror of the repository and
+// the workspace of the task which is created from the mirror as git worktree.
 type GitRepository struct {
-	TemplateID int
+	TaskID     int
 	Repository db.Repository
 	Logger     Logger
+
+	// Context stops running git commands when it is cancelled.
+	Context context.Context
 }
 
 func (r GitRepository) makeCmd(targetDir GitRepositoryDirType, args ...string) *exec.Cmd {
@@ -38,7 +51,9 @@ func (r GitRepository) makeCmd(targetDir GitRepositoryDirType, args ...string) *
 	switch targetDir {
 	case GitRepositoryTmpDir:
 		cmd.Dir = util.Config.TmpPath
-	case GitRepositoryRepoDir:
+	case GitRepositoryMirrorDir:
+		cmd.Dir = r.Repository.GetMirrorPath()
+	case GitRepositoryWorkspaceDir:
 		cmd.Dir = r.GetFullPath()
 	default:
 		panic("unknown Repository directory type")
@@ -61,7 +76,7 @@ func (r GitRepository) run(targetDir GitRepositoryDirType, args ...string) error
 
 	r.Logger.LogCmd(cmd)
 
-	return cmd.Run()
+	return runCommand(r.Context, cmd)
 }
 
 func (r GitRepository) output(targetDir GitRepositoryDirType, args ...string) (out string, err error) {
@@ -72,54 +87,136 @@ func (r GitRepository) output(targetDir GitRepositoryDirType, args ...string) (o
 
 	defer r.Repository.SSHKey.Destroy() //nolint: errcheck
 
-	bytes, err := r.makeCmd(targetDir, args...).Output()
+	var stdout bytes.Buffer
+
+	cmd := r.makeCmd(targetDir, args...)
+	cmd.Stdout = &stdout
+
+	err = runCommand(r.Context, cmd)
 	if err != nil {
 		return
 	}
-	out = strings.Trim(string(bytes), " \n")
+	out = strings.Trim(stdout.String(), " \n")
 	return
 }
 
-func (r GitRepository) Clone() error {
+func (r GitRepository) lockMirror() func() {
+	return r.Repository.LockMirror()
+}
+
+// cloneMirror clones the repository to the temporary directory and replaces the mirror with it,
+// so the mirror is never left half cloned.
+func (r GitRepository) cloneMirror() error {
 	r.Logger.Log("Cloning Repository " + r.Repository.GitURL)
 
-	return r.run(GitRepositoryTmpDir,
+	clonePath, err := ioutil.TempDir(util.Config.TmpPath, "mirror_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(clonePath) //nolint: errcheck
+
+	// mirror is read by tasks running in containers
+	if err = os.Chmod(clonePath, 0755); err != nil {
+		return err
+	}
+
+	err = r.run(GitRepositoryTmpDir,
 		"clone",
-		"--recursive",
-		"--branch",
-		r.Repository.GitBranch,
+		"--mirror",
 		r.Repository.GetGitURL(),
-		r.Repository.GetDirName(r.TemplateID))
+		clonePath)
+	if err != nil {
+		return err
+	}
+
+	if err = os.RemoveAll(r.Repository.GetMirrorPath()); err != nil {
+		return err
+	}
+
+	return os.Rename(clonePath, r.Repository.GetMirrorPath())
+}
+
+// isMirrorValid returns false if the mirror is not a git repository or some of its objects are missing.
+func (r GitRepository) isMirrorValid() bool {
+	return r.run(GitRepositoryMirrorDir, "fsck", "--connectivity-only", "--no-dangling") == nil
 }
 
-func (r GitRepository) Pull() error {
+// UpdateMirror clones the mirror of the repository or fetches new commits to it.
+func (r GitRepository) UpdateMirror() error {
+	unlock := r.lockMirror()
+	defer unlock()
+
+	if _, err := os.Stat(r.Repository.GetMirrorPath()); err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		return r.cloneMirror()
+	}
+
 	r.Logger.Log("Updating Repository " + r.Repository.GitURL)
 
-	return r.run(GitRepositoryRepoDir, "pull", "origin", r.Repository.GitBranch)
+	err := r.run(GitRepositoryMirrorDir, "fetch", "--prune", r.Repository.GetGitURL(), "+refs/*:refs/*")
+	if err == nil {
+		return nil
+	}
+
+	// workspaces of other tasks use the mirror, so it is cloned again only if it is broken
+	if r.Context != nil && r.Context.Err() != nil {
+		return r.Context.Err()
+	}
+
+	if r.isMirrorValid() {
+		return err
+	}
+
+	r.Logger.Log("Repository mirror is broken, cloning it again")
+
+	return r.cloneMirror()
 }
 
-func (r GitRepository) Checkout(target string) error {
-	r.Logger.Log("Checkout repository to " + target)
+// CreateWorkspace makes the directory of the task checked out to the commit.
+func (r GitRepository) CreateWorkspace(commitHash string) error {
+	unlock := r.lockMirror()
+	defer unlock()
+
+	r.Logger.Log("Checkout repository to " + commitHash)
+
+	// workspace can be left by the previous run of the task
+	if err := os.RemoveAll(r.GetFullPath()); err != nil {
+		return err
+	}
+
+	if err := r.run(GitRepositoryMirrorDir, "worktree", "prune"); err != nil {
+		return err
+	}
+
+	if err := r.run(GitRepositoryMirrorDir, "worktree", "add", "--detach", r.GetFullPath(), commitHash); err != nil {
+		return err
+	}
 
-	return r.run(GitRepositoryRepoDir, "checkout", target)
+	return r.run(GitRepositoryWorkspaceDir, "submodule", "update", "--init", "--recursive")
 }
 
-func (r GitRepository) CanBePulled() bool {
-	err := r.run(GitRepositoryRepoDir, "fetch")
-	if err != nil {
-		return false
+// DestroyWorkspace removes the directory of the task.
+func (r GitRepository) DestroyWorkspace() error {
+	unlock := r.lockMirror()
+	defer unlock()
+
+	if err := r.run(GitRepositoryMirrorDir, "worktree", "remove", "--force", r.GetFullPath()); err == nil {
+		return nil
 	}
 
-	err = r.run(GitRepositoryRepoDir,
-		"merge-base", "--is-ancestor", "HEAD", "origin/"+r.Repository.GitBranch)
+	if err := os.RemoveAll(r.GetFullPath()); err != nil {
+		return err
+	}
 
-	return err == nil
+	return r.run(GitRepositoryMirrorDir, "worktree", "prune")
 }
 
-func (r GitRepository) GetLastCommitMessage() (msg string, err error) {
+func (r GitRepository) GetCommitMessage(commitHash string) (msg string, err error) {
 	r.Logger.Log("Get current commit message")
 
-	msg, err = r.output(GitRepositoryRepoDir, "show-branch", "--no-name", "HEAD")
+	msg, err = r.output(GitRepositoryMirrorDir, "show", "-s", "--format=%s", commitHash)
 	if err != nil {
 		return
 	}
@@ -131,19 +228,33 @@ func (r GitRepository) GetLastCommitMessage() (msg string, err error) {
 	return
 }
 
+// GetLastCommitHash returns the last commit of the repository branch in the mirror.
 func (r GitRepository) GetLastCommitHash() (hash string, err error) {
 	r.Logger.Log("Get current commit hash")
-	hash, err = r.output(GitRepositoryRepoDir, "rev-parse", "HEAD")
+	hash, err = r.output(GitRepositoryMirrorDir, "rev-parse", "refs/heads/"+r.Repository.GitBranch)
 	return
 }
 
-func (r GitRepository) ValidateRepo() error {
-	_, err := os.Stat(r.GetFullPath())
-	return err
+// GetLastTag returns the latest tag reachable from the commit checked out in the workspace.
+func (r GitRepository) GetLastTag() (tag string, err error) {
+	r.Logger.Log("Get latest tag")
+
+	// git describe fails with the same status if there are no tags or something else is wrong
+	tags, err := r.output(GitRepositoryWorkspaceDir, "tag", "--merged", "HEAD")
+	if err != nil {
+		return
+	}
+	if tags == "" {
+		err = ErrNoTags
+		return
+	}
+
+	tag, err = r.output(GitRepositoryWorkspaceDir, "describe", "--tags", "--abbrev=0")
+	return
 }
 
 func (r GitRepository) GetFullPath() (path string) {
-	path = r.Repository.GetFullPath(r.TemplateID)
+	path = r.Repository.GetWorkspacePath(r.TaskID)
 	return
 }
 
@@ -0,0 +1,128 @@
+package lib
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"testing"
+
+	"github.com/ansible-semaphore/semaphore/db"
+	"github.com/ansible-semaphore/semaphore/util"
+)
+
+type testLogger struct{}
+
+func (l testLogger) Log(msg string) {}
+
+func (l testLogger) LogCmd(cmd *exec.Cmd) {}
+
+func createTestMirror(t *testing.T) (GitRepository, string) {
+	tmpPath, err := ioutil.TempDir("", "semaphore_mirror")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	originPath := path.Join(tmpPath, "origin")
+
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", originPath, "-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "init.defaultBranch=master"}, args...)...)
+		if out, gitErr := cmd.CombinedOutput(); gitErr != nil {
+			t.Fatal(gitErr, string(out))
+		}
+	}
+
+	if err = os.MkdirAll(originPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	git("init")
+	git("commit", "--allow-empty", "-m", "init")
+
+	repo := GitRepository{
+		Logger: testLogger{},
+		Repository: db.Repository{
+			ID:        1,
+			GitURL:    originPath,
+			GitBranch: "master",
+			SSHKey:    db.AccessKey{Type: db.AccessKeyNone},
+		},
+	}
+
+	util.Config = &util.ConfigType{TmpPath: tmpPath}
+
+	if err = repo.UpdateMirror(); err != nil {
+		t.Fatal(err)
+	}
+
+	return repo, tmpPath
+}
+
+// markMirror puts a file to the mirror, so tests can check that the mirror was not cloned again.
+func markMirror(t *testing.T, repo GitRepository) string {
+	marker := path.Join(repo.Repository.GetMirrorPath(), "semaphore_marker")
+	if err := ioutil.WriteFile(marker, []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return marker
+}
+
+func TestUpdateMirrorKeepsMirrorIfFetchFails(t *testing.T) {
+	oldConfig := util.Config
+	defer func() { util.Config = oldConfig }()
+
+	repo, tmpPath := createTestMirror(t)
+	defer os.RemoveAll(tmpPath) //nolint: errcheck
+
+	marker := markMirror(t, repo)
+
+	// the remote is unavailable
+	repo.Repository.GitURL = path.Join(tmpPath, "missing")
+
+	if err := repo.UpdateMirror(); err == nil {
+		t.Fatal("failed fetch must be reported")
+	}
+
+	if _, err := os.Stat(marker); err != nil {
+		t.Fatal("mirror must be kept if the remote is unavailable")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	repo.Context = ctx
+
+	if err := repo.UpdateMirror(); err != context.Canceled {
+		t.Fatal("stopped task must return context error", err)
+	}
+
+	if _, err := os.Stat(marker); err != nil {
+		t.Fatal("mirror must be kept if the task is stopped")
+	}
+}
+
+func TestUpdateMirrorClonesBrokenMirror(t *testing.T) {
+	oldConfig := util.Config
+	defer func() { util.Config = oldConfig }()
+
+	repo, tmpPath := createTestMirror(t)
+	defer os.RemoveAll(tmpPath) //nolint: errcheck
+
+	marker := markMirror(t, repo)
+
+	if err := os.RemoveAll(path.Join(repo.Repository.GetMirrorPath(), "objects")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.UpdateMirror(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Fatal("broken mirror must be cloned again")
+	}
+
+	if _, err := repo.GetLastCommitHash(); err != nil {
+		t.Fatal("mirror must contain the branch after it is cloned again", err)
+	}
+}
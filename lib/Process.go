@@ -0,0 +1,63 @@
+package lib
+
+import (
+	"context"
+	"os/exec"
+	"time"
+
+	"github.com/ansible-semaphore/semaphore/util"
+)
+
+// getStopGracePeriod returns time which stopped commands have to exit after interrupt.
+func getStopGracePeriod() time.Duration {
+	if util.Config == nil {
+		return 0
+	}
+	return time.Duration(util.Config.TaskStopGracePeriod) * time.Second
+}
+
+// runCommand runs the command in its own process group, so processes started by the command,
+// like ssh connections of ansible, are stopped with it. If ctx is cancelled, the group
+// is interrupted and killed if it doesn't exit during the grace period.
+func runCommand(ctx context.Context, cmd *exec.Cmd) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	setProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+
+	interruptProcessGroup(cmd.Process)
+
+	timer := time.NewTimer(getStopGracePeriod())
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return ctx.Err()
+	case <-timer.C:
+	}
+
+	killProcessGroup(cmd.Process)
+	<-done
+
+	return ctx.Err()
+}
@@ -0,0 +1,34 @@
+//go:build !windows
+
+package lib
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestRunCommandCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// the child ignores SIGINT, so it is stopped only by SIGKILL sent to the group
+	cmd := exec.Command("sh", "-c", "trap '' INT; sleep 30 & wait")
+
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := runCommand(ctx, cmd)
+
+	if err != context.Canceled {
+		t.Fatal("cancelled command must return context error", err)
+	}
+
+	if time.Since(start) > 10*time.Second {
+		t.Fatal("process group must be killed")
+	}
+
+	if err = runCommand(ctx, exec.Command("true")); err != context.Canceled {
+		t.Fatal("command must not be started with cancelled context")
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !windows
+
+package lib
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+func interruptProcessGroup(process *os.Process) {
+	// negative pid addresses the whole process group
+	syscall.Kill(-process.Pid, syscall.SIGINT) //nolint: errcheck
+}
+
+func killProcessGroup(process *os.Process) {
+	syscall.Kill(-process.Pid, syscall.SIGKILL) //nolint: errcheck
+}
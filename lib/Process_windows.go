@@ -0,0 +1,19 @@
+package lib
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Process groups and interrupts are not supported on Windows,
+// so only the started process is killed.
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func interruptProcessGroup(process *os.Process) {
+	process.Kill() //nolint: errcheck
+}
+
+func killProcessGroup(process *os.Process) {
+	process.Kill() //nolint: errcheck
+}
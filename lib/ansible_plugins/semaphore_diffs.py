@@ -0,0 +1,77 @@
+# Stores per-host diffs of the playbook run for Semaphore.
+from __future__ import (absolute_import, division, print_function)
+__metaclass__ = type
+
+DOCUMENTATION = '''
+    name: semaphore_diffs
+    type: aggregate
+    short_description: Stores per-host diffs of the playbook run for Semaphore
+    description:
+      - Writes diffs reported by tasks run with --diff to the JSON file
+        specified by SEMAPHORE_DIFFS_FILE environment variable.
+    requirements:
+      - enable in configuration
+'''
+
+import json
+import os
+
+from ansible.module_utils.six import string_types
+from ansible.plugins.callback import CallbackBase
+
+
+def _to_text(value):
+    if value is None:
+        return ''
+    if isinstance(value, string_types):
+        return value
+    return json.dumps(value, indent=2, sort_keys=True)
+
+
+class CallbackModule(CallbackBase):
+    CALLBACK_VERSION = 2.0
+    CALLBACK_TYPE = 'aggregate'
+    CALLBACK_NAME = 'semaphore_diffs'
+    CALLBACK_NEEDS_ENABLED = True
+    CALLBACK_NEEDS_WHITELIST = True
+
+    def __init__(self):
+        super(CallbackModule, self).__init__()
+        self.diffs = []
+
+    def _add_diffs(self, host, task, diff):
+        if not diff:
+            return
+        if not isinstance(diff, list):
+            diff = [diff]
+        for d in diff:
+            if not d:
+                continue
+            self.diffs.append({
+                'host': host,
+                'task_name': task,
+                'before_header': _to_text(d.get('before_header')),
+                'before': _to_text(d.get('before')),
+                'after_header': _to_text(d.get('after_header')),
+                'after': _to_text(d.get('after')),
+                'prepared': _to_text(d.get('prepared')),
+            })
+
+    def v2_on_file_diff(self, result):
+        host = result._host.get_name()
+        task = result._task.get_name()
+
+        if 'results' in result._result:
+            for res in result._result['results']:
+                if res.get('changed', False):
+                    self._add_diffs(host, task, res.get('diff'))
+        elif result._result.get('changed', False):
+            self._add_diffs(host, task, result._result.get('diff'))
+
+    def v2_playbook_on_stats(self, stats):
+        path = os.environ.get('SEMAPHORE_DIFFS_FILE')
+        if not path:
+            return
+
+        with open(path, 'w') as f:
+            json.dump(self.diffs, f)
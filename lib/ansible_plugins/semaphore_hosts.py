@@ -0,0 +1,62 @@
+# Stores per-host results of the playbook run for Semaphore.
+from __future__ import (absolute_import, division, print_function)
+__metaclass__ = type
+
+DOCUMENTATION = '''
+    name: semaphore_hosts
+    type: aggregate
+    short_description: Stores per-host results of the playbook run for Semaphore
+    description:
+      - Writes summary of each host to the JSON file specified by SEMAPHORE_HOSTS_FILE environment variable.
+    requirements:
+      - enable in configuration
+'''
+
+import json
+import os
+
+from ansible.plugins.callback import CallbackBase
+
+
+class CallbackModule(CallbackBase):
+    CALLBACK_VERSION = 2.0
+    CALLBACK_TYPE = 'aggregate'
+    CALLBACK_NAME = 'semaphore_hosts'
+    CALLBACK_NEEDS_ENABLED = True
+    CALLBACK_NEEDS_WHITELIST = True
+
+    def __init__(self):
+        super(CallbackModule, self).__init__()
+        self.failed_tasks = {}
+
+    def _add_failed_task(self, result):
+        host = result._host.get_name()
+        self.failed_tasks.setdefault(host, []).append(result._task.get_name())
+
+    def v2_runner_on_failed(self, result, ignore_errors=False):
+        if not ignore_errors:
+            self._add_failed_task(result)
+
+    def v2_runner_on_unreachable(self, result):
+        self._add_failed_task(result)
+
+    def v2_playbook_on_stats(self, stats):
+        path = os.environ.get('SEMAPHORE_HOSTS_FILE')
+        if not path:
+            return
+
+        hosts = []
+        for host in sorted(stats.processed.keys()):
+            summary = stats.summarize(host)
+            hosts.append({
+                'host': host,
+                'ok': summary['ok'],
+                'changed': summary['changed'],
+                'failed': summary['failures'],
+                'unreachable': summary['unreachable'],
+                'skipped': summary['skipped'],
+                'failed_tasks': self.failed_tasks.get(host, []),
+            })
+
+        with open(path, 'w') as f:
+            json.dump(hosts, f)
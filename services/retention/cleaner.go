@@ -0,0 +1,145 @@
+package retention
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/ansible-semaphore/semaphore/db"
+	"github.com/ansible-semaphore/semaphore/lib"
+	"github.com/ansible-semaphore/semaphore/util"
+	"time"
+)
+
+// compressDelay gives the task pool time to write the last output records
+// of a finished task before the output is compressed.
+const compressDelay = time.Minute
+
+// Cleaner periodically enforces task retention policies of all projects.
+type Cleaner struct {
+	store db.Store
+}
+
+func CreateCleaner(store db.Store) Cleaner {
+	return Cleaner{
+		store: store,
+	}
+}
+
+func (c *Cleaner) Run() {
+	ticker := time.NewTicker(time.Duration(util.Config.TaskRetention.Interval) * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		c.Clean()
+		<-ticker.C
+	}
+}
+
+// Clean applies retention policies to all projects once.
+func (c *Cleaner) Clean() {
+	projects, err := c.store.GetAllProjects()
+
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	now := time.Now()
+
+	for _, project := range projects {
+		err = c.cleanProject(project, now)
+
+		if err != nil {
+			log.Error(err)
+		}
+	}
+}
+
+func getMaxTasksPerTemplate(project db.Project) int {
+	if project.MaxTasksPerTemplate != 0 {
+		return project.MaxTasksPerTemplate
+	}
+	return util.Config.TaskRetention.MaxTasksPerTemplate
+}
+
+func getOutputRetentionDays(project db.Project) int {
+	if project.OutputRetentionDays != 0 {
+		return project.OutputRetentionDays
+	}
+	return util.Config.TaskRetention.OutputRetentionDays
+}
+
+func (c *Cleaner) cleanProject(project db.Project, now time.Time) error {
+	if maxTasks := getMaxTasksPerTemplate(project); maxTasks > 0 {
+		err := c.deleteOldTasks(project.ID, maxTasks)
+		if err != nil {
+			return err
+		}
+	}
+
+	if days := getOutputRetentionDays(project); days > 0 {
+		err := c.store.DeleteTaskOutputsBefore(project.ID, now.AddDate(0, 0, -days))
+		if err != nil {
+			return err
+		}
+	}
+
+	if util.Config.TaskRetention.CompressOutput {
+		err := c.store.CompressTaskOutputsBefore(project.ID, now.Add(-compressDelay))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteOldTasks deletes finished tasks of every template of the project
+// except the latest maxTasks ones.
+func (c *Cleaner) deleteOldTasks(projectID int, maxTasks int) error {
+	templates, err := c.store.GetTemplates(projectID, db.TemplateFilter{}, db.RetrieveQueryParams{})
+
+	if err != nil {
+		return err
+	}
+
+	for _, tpl := range templates {
+		var tasks []db.TaskWithTpl
+		tasks, err = c.store.GetTemplateTasks(projectID, tpl.ID, db.RetrieveQueryParams{})
+
+		if err != nil {
+			return err
+		}
+
+		if len(tasks) <= maxTasks {
+			continue
+		}
+
+		deleted := 0
+
+		for _, task := range tasks[maxTasks:] {
+			switch task.Status {
+			case db.TaskWaitingStatus, db.TaskRunningStatus, db.TaskStoppingStatus, db.TaskAwaitingApprovalStatus:
+				continue
+			}
+
+			err = c.store.DeleteTaskWithOutputs(projectID, task.ID)
+
+			if err != nil {
+				return err
+			}
+
+			err = lib.GetArtifactStore().Delete(task.ID)
+
+			if err != nil {
+				return err
+			}
+
+			deleted++
+		}
+
+		if deleted > 0 {
+			log.Info("Deleted ", deleted, " old tasks of template ", tpl.ID, " of project ", projectID)
+		}
+	}
+
+	return nil
+}
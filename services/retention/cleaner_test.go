@@ -0,0 +1,66 @@
+package retention
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ansible-semaphore/semaphore/db"
+	"github.com/ansible-semaphore/semaphore/db/bolt"
+	"github.com/ansible-semaphore/semaphore/util"
+)
+
+func TestDeleteOldTasksKeepsAwaitingApproval(t *testing.T) {
+	artifactsPath, err := ioutil.TempDir("", "semaphore_artifacts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(artifactsPath) //nolint: errcheck
+
+	oldConfig := util.Config
+	util.Config = &util.ConfigType{ArtifactsPath: artifactsPath}
+	defer func() { util.Config = oldConfig }()
+
+	store := bolt.CreateTestStore()
+
+	proj, err := store.CreateProject(db.Project{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tpl, err := store.CreateTemplate(db.Template{Name: "Deploy", Playbook: "deploy.yml", ProjectID: proj.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statuses := []db.TaskStatus{db.TaskAwaitingApprovalStatus, db.TaskSuccessStatus, db.TaskSuccessStatus}
+	created := make([]db.Task, 0, len(statuses))
+
+	for _, status := range statuses {
+		var task db.Task
+		task, err = store.CreateTask(db.Task{TemplateID: tpl.ID, ProjectID: proj.ID, Status: status})
+		if err != nil {
+			t.Fatal(err)
+		}
+		created = append(created, task)
+	}
+
+	c := CreateCleaner(&store)
+
+	err = c.deleteOldTasks(proj.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = store.GetTask(proj.ID, created[0].ID); err != nil {
+		t.Fatal("task awaiting approval must not be deleted")
+	}
+
+	if _, err = store.GetTask(proj.ID, created[1].ID); err != db.ErrNotFound {
+		t.Fatal("old finished task must be deleted")
+	}
+
+	if _, err = store.GetTask(proj.ID, created[2].ID); err != nil {
+		t.Fatal("latest task must not be deleted")
+	}
+}
@@ -0,0 +1,13 @@
+package runners
+
+import "github.com/ansible-semaphore/semaphore/services/tasks"
+
+// NewJobLogger returns the logger which a job of the task uses to talk to the server.
+func (p *JobPool) NewJobLogger(config RunnerConfig, taskID int) tasks.JobLogger {
+	p.config = &config
+	return &runningJob{
+		status: "running",
+		taskID: taskID,
+		pool:   p,
+	}
+}
@@ -0,0 +1,418 @@
+package runners
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/ansible-semaphore/semaphore/db"
+	"github.com/ansible-semaphore/semaphore/lib"
+	"github.com/ansible-semaphore/semaphore/services/tasks"
+	"github.com/ansible-semaphore/semaphore/util"
+)
+
+// RunnerRegistration is sent by a runner to register on the server.
+type RunnerRegistration struct {
+	RegistrationToken string `json:"registration_token"`
+}
+
+// RunnerConfig is issued to a runner during registration.
+// The runner stores it in the file specified by runner.config_file option.
+type RunnerConfig struct {
+	RunnerID int    `json:"runner_id"`
+	Token    string `json:"token"`
+}
+
+type runningJob struct {
+	status        db.TaskStatus
+	logRecords    []tasks.RemoteLogRecord
+	commitHash    *string
+	commitMessage string
+
+	unreachableHosts bool
+	hosts            []db.TaskHost
+	diffs            []db.TaskDiff
+	annotations      []db.TaskAnnotation
+	version          *string
+
+	taskID int
+	pool   *JobPool
+
+	job   *tasks.LocalJob
+	mutex sync.Mutex
+}
+
+func (j *runningJob) Log(msg string) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.logRecords = append(j.logRecords, tasks.RemoteLogRecord{
+		Time:    time.Now(),
+		Message: msg,
+	})
+}
+
+func (j *runningJob) logPipe(reader *bufio.Reader) {
+	line, err := tasks.Readln(reader)
+	for err == nil {
+		j.Log(line)
+		line, err = tasks.Readln(reader)
+	}
+}
+
+func (j *runningJob) LogCmd(cmd *exec.Cmd) {
+	stderr, _ := cmd.StderrPipe()
+	stdout, _ := cmd.StdoutPipe()
+
+	go j.logPipe(bufio.NewReader(stderr))
+	go j.logPipe(bufio.NewReader(stdout))
+}
+
+func (j *runningJob) SetCommit(hash string, message string) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.commitHash = &hash
+	j.commitMessage = message
+}
+
+func (j *runningJob) SetHostResults(hosts []db.TaskHost) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.hosts = hosts
+}
+
+func (j *runningJob) SetDiffs(diffs []db.TaskDiff) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.diffs = diffs
+}
+
+func (j *runningJob) SetAnnotations(annotations []db.TaskAnnotation) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.annotations = annotations
+}
+
+func (j *runningJob) SetVersion(version string) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.version = &version
+}
+
+// AddArtifact uploads a file produced by the job to the server.
+func (j *runningJob) AddArtifact(name string, content io.Reader) error {
+	path := "/internal/runners/" + strconv.Itoa(j.pool.config.RunnerID) +
+		"/tasks/" + strconv.Itoa(j.taskID) + "/artifacts?name=" + url.QueryEscape(name)
+
+	resp, err := j.pool.doRequest("POST", path, content, "application/octet-stream")
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+// GetBuildArtifact downloads an artifact of the build task from the server.
+func (j *runningJob) GetBuildArtifact(artifact db.TaskArtifact) (io.ReadCloser, error) {
+	path := "/internal/runners/" + strconv.Itoa(j.pool.config.RunnerID) +
+		"/tasks/" + strconv.Itoa(j.taskID) + "/artifacts/" + strconv.Itoa(artifact.ID)
+
+	resp, err := j.pool.doRequest("GET", path, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+func (j *runningJob) setStatus(status db.TaskStatus) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.status = status
+}
+
+func (j *runningJob) isFinished() bool {
+	switch j.status {
+	case db.TaskSuccessStatus, db.TaskFailStatus, db.TaskStoppedStatus:
+		return true
+	default:
+		return false
+	}
+}
+
+// JobPool pulls tasks from the Semaphore server, runs them locally
+// and sends their output back to the server.
+type JobPool struct {
+	config *RunnerConfig
+	client *http.Client
+
+	jobs  map[int]*runningJob
+	mutex sync.Mutex
+}
+
+func CreateJobPool() JobPool {
+	return JobPool{
+		client: &http.Client{Timeout: time.Minute},
+		jobs:   make(map[int]*runningJob),
+	}
+}
+
+func (p *JobPool) Run() {
+	if err := p.register(); err != nil {
+		log.Error(err)
+		return
+	}
+
+	go p.sendProgress()
+
+	for {
+		if p.runningJobsCount() >= util.Config.Runner.MaxParallelTasks {
+			time.Sleep(time.Second)
+			continue
+		}
+
+		var state tasks.RunnerState
+
+		err := p.sendRequest("GET", "/internal/runners/"+strconv.Itoa(p.config.RunnerID), nil, &state)
+		if err != nil {
+			log.Error(err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		for _, data := range state.NewJobs {
+			p.startJob(data)
+		}
+	}
+}
+
+func (p *JobPool) runningJobsCount() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return len(p.jobs)
+}
+
+func (p *JobPool) startJob(data tasks.RemoteJobData) {
+	j := &runningJob{
+		status: db.TaskRunningStatus,
+		taskID: data.Task.ID,
+		pool:   p,
+	}
+
+	j.job = data.CreateLocalJob(j)
+
+	p.mutex.Lock()
+	p.jobs[data.Task.ID] = j
+	p.mutex.Unlock()
+
+	log.Info("Task " + strconv.Itoa(data.Task.ID) + " started")
+
+	go func() {
+		err := j.job.Run(data.Username, data.IncomingVersion)
+
+		if err != nil {
+			j.Log("Running playbook failed: " + err.Error())
+			j.mutex.Lock()
+			j.unreachableHosts = lib.IsUnreachableHostsError(err)
+			j.mutex.Unlock()
+			j.setStatus(db.TaskFailStatus)
+		} else {
+			j.setStatus(db.TaskSuccessStatus)
+		}
+
+		log.Info("Task " + strconv.Itoa(data.Task.ID) + " finished")
+	}()
+}
+
+// sendProgress sends output and state of the running jobs to the server every second.
+func (p *JobPool) sendProgress() {
+	for {
+		time.Sleep(time.Second)
+
+		var progress tasks.RunnerProgress
+		sent := make(map[int]int)
+
+		p.mutex.Lock()
+		for id, j := range p.jobs {
+			j.mutex.Lock()
+			progress.Jobs = append(progress.Jobs, tasks.RemoteJobProgress{
+				ID:               id,
+				Status:           j.status,
+				LogRecords:       append([]tasks.RemoteLogRecord{}, j.logRecords...),
+				CommitHash:       j.commitHash,
+				CommitMessage:    j.commitMessage,
+				UnreachableHosts: j.unreachableHosts,
+				Hosts:            j.hosts,
+				Diffs:            j.diffs,
+				Annotations:      j.annotations,
+				Version:          j.version,
+			})
+			sent[id] = len(j.logRecords)
+			j.mutex.Unlock()
+		}
+		p.mutex.Unlock()
+
+		if len(progress.Jobs) == 0 {
+			continue
+		}
+
+		var res tasks.RunnerProgressResult
+
+		err := p.sendRequest("PUT", "/internal/runners/"+strconv.Itoa(p.config.RunnerID), progress, &res)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+
+		states := make(map[int]db.TaskStatus)
+		for _, s := range res.Jobs {
+			states[s.ID] = s.Status
+		}
+
+		p.mutex.Lock()
+		for _, jp := range progress.Jobs {
+			j := p.jobs[jp.ID]
+
+			j.mutex.Lock()
+			j.logRecords = j.logRecords[sent[jp.ID]:]
+			if jp.CommitHash != nil {
+				j.commitHash = nil
+			}
+			if jp.Hosts != nil {
+				j.hosts = nil
+			}
+			if jp.Diffs != nil {
+				j.diffs = nil
+			}
+			if jp.Annotations != nil {
+				j.annotations = nil
+			}
+			if jp.Version != nil {
+				j.version = nil
+			}
+			finished := j.isFinished() && jp.Status == j.status
+			j.mutex.Unlock()
+
+			if finished {
+				delete(p.jobs, jp.ID)
+				continue
+			}
+
+			status, ok := states[jp.ID]
+			if !ok || status != db.TaskRunningStatus {
+				j.job.Kill()
+			}
+		}
+		p.mutex.Unlock()
+	}
+}
+
+func (p *JobPool) register() (err error) {
+	configFile := util.Config.Runner.ConfigFile
+
+	if configFile == "" {
+		return fmt.Errorf("runner config_file is not specified")
+	}
+
+	p.config = &RunnerConfig{}
+
+	content, err := ioutil.ReadFile(configFile)
+
+	if err == nil {
+		return json.Unmarshal(content, p.config)
+	}
+
+	if !os.IsNotExist(err) {
+		return
+	}
+
+	if util.Config.Runner.RegistrationToken == "" {
+		return fmt.Errorf("runner is not registered and registration_token is not specified")
+	}
+
+	log.Info("Registering runner")
+
+	err = p.sendRequest("POST", "/internal/runners", RunnerRegistration{
+		RegistrationToken: util.Config.Runner.RegistrationToken,
+	}, p.config)
+
+	if err != nil {
+		return
+	}
+
+	content, err = json.Marshal(p.config)
+	if err != nil {
+		return
+	}
+
+	return ioutil.WriteFile(configFile, content, 0600)
+}
+
+func (p *JobPool) sendRequest(method string, path string, body interface{}, out interface{}) error {
+	var reqBody bytes.Buffer
+
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			return err
+		}
+	}
+
+	resp, err := p.doRequest(method, path, &reqBody, "application/json")
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close() //nolint: errcheck
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// doRequest sends the request to the server. The caller must close body of the response.
+func (p *JobPool) doRequest(method string, path string, body io.Reader, contentType string) (*http.Response, error) {
+	req, err := http.NewRequest(method, util.Config.Runner.APIURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if contentType != "" {
+		req.Header.Set("content-type", contentType)
+	}
+
+	if p.config != nil && p.config.Token != "" {
+		req.Header.Set("X-Runner-Token", p.config.Token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		resp.Body.Close() //nolint: errcheck
+		return nil, fmt.Errorf("server responded with status %d to %s %s", resp.StatusCode, method, path)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,168 @@
+package runners_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ansible-semaphore/semaphore/api"
+	"github.com/ansible-semaphore/semaphore/db"
+	"github.com/ansible-semaphore/semaphore/db/bolt"
+	"github.com/ansible-semaphore/semaphore/lib"
+	"github.com/ansible-semaphore/semaphore/services/runners"
+	"github.com/ansible-semaphore/semaphore/services/tasks"
+	"github.com/ansible-semaphore/semaphore/util"
+	"github.com/gorilla/context"
+)
+
+func TestRunningJobArtifacts(t *testing.T) {
+	artifactsPath, err := ioutil.TempDir("", "semaphore_artifacts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(artifactsPath) //nolint: errcheck
+
+	store := bolt.CreateTestStore()
+	pool := tasks.CreateTaskPool(&store)
+
+	route := api.Route()
+	route.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			context.Set(r, "store", &store)
+			context.Set(r, "task_pool", &pool)
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	server := httptest.NewServer(route)
+	defer server.Close()
+
+	oldConfig := util.Config
+	util.Config = &util.ConfigType{
+		UseRemoteRunner:        true,
+		MaxParallelTasks:       1,
+		RunnerHeartbeatTimeout: 60,
+		ArtifactsPath:          artifactsPath,
+		TmpPath:                artifactsPath,
+	}
+	util.Config.Runner.APIURL = server.URL + "/api"
+	defer func() { util.Config = oldConfig }()
+
+	go pool.Run()
+
+	proj, err := store.CreateProject(db.Project{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := store.CreateAccessKey(db.AccessKey{ProjectID: &proj.ID, Type: db.AccessKeyNone})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := store.CreateRepository(db.Repository{
+		ProjectID: proj.ID,
+		SSHKeyID:  key.ID,
+		Name:      "Test",
+		GitURL:    "git@example.com:test/test",
+		GitBranch: "master",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inv, err := store.CreateInventory(db.Inventory{ProjectID: proj.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buildTpl, err := store.CreateTemplate(db.Template{
+		Name:         "Build",
+		Playbook:     "build.yml",
+		ProjectID:    proj.ID,
+		RepositoryID: repo.ID,
+		InventoryID:  inv.ID,
+		Type:         db.TemplateBuild,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deployTpl, err := store.CreateTemplate(db.Template{
+		Name:            "Deploy",
+		Playbook:        "deploy.yml",
+		ProjectID:       proj.ID,
+		RepositoryID:    repo.ID,
+		InventoryID:     inv.ID,
+		Type:            db.TemplateDeploy,
+		BuildTemplateID: &buildTpl.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	build, err := store.CreateTask(db.Task{TemplateID: buildTpl.ID, ProjectID: proj.ID, Status: db.TaskSuccessStatus})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	size, err := lib.GetArtifactStore().Put(build.ID, "app.tar", strings.NewReader("build"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buildArtifact, err := store.CreateTaskArtifact(db.TaskArtifact{TaskID: build.ID, Name: "app.tar", Size: size})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runner, err := store.CreateRunner(db.Runner{Token: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deploy, err := pool.AddTask(db.Task{TemplateID: deployTpl.ID, BuildTaskID: &build.ID}, nil, proj.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := pool.GetRunnerState(runner.ID, 5*time.Second, nil)
+	if len(state.NewJobs) != 1 || state.NewJobs[0].Task.ID != deploy.ID {
+		t.Fatal("runner must take the deploy task")
+	}
+
+	defer pool.StopTask(deploy) //nolint: errcheck
+
+	jobPool := runners.CreateJobPool()
+	job := jobPool.NewJobLogger(runners.RunnerConfig{RunnerID: runner.ID, Token: runner.Token}, deploy.ID)
+
+	content, err := job.GetBuildArtifact(buildArtifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(content)
+	content.Close() //nolint: errcheck
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "build" {
+		t.Fatal("unexpected content of the build artifact: " + string(b))
+	}
+
+	err = job.AddArtifact("report.txt", strings.NewReader("deployed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	artifacts, err := store.GetTaskArtifacts(proj.ID, deploy.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(artifacts) != 1 || artifacts[0].Name != "report.txt" {
+		t.Fatal("artifact uploaded by the runner must be stored")
+	}
+}
@@ -28,7 +28,6 @@ func (r ScheduleRunner) tryUpdateScheduleCommitHash(schedule db.Schedule) (updat
 
 	remoteHash, err := lib.GitRepository{
 		Logger:     nil,
-		TemplateID: schedule.TemplateID,
 		Repository: repo,
 	}.GetLastRemoteCommitHash()
 
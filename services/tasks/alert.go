@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 
+	log "github.com/Sirupsen/logrus"
 	"github.com/ansible-semaphore/semaphore/util"
 )
 
@@ -16,6 +17,11 @@ const emailTemplate = `Subject: Task '{{ .Name }}' failed
 Task {{ .TaskID }} with template '{{ .Name }}' has failed!
 Task log: <a href='{{ .TaskURL }}'>{{ .TaskURL }}</a>`
 
+const approvalEmailTemplate = `Subject: Task '{{ .Name }}' awaits approval
+
+Task {{ .TaskID }} with template '{{ .Name }}' was started by {{ .Author }} and awaits your approval.
+Task: <a href='{{ .TaskURL }}'>{{ .TaskURL }}</a>`
+
 const telegramTemplate = `{"chat_id": "{{ .ChatID }}","parse_mode":"HTML","text":"<code>{{ .Name }}</code>\n#{{ .TaskID }} <b>{{ .TaskResult }}</b> <code>{{ .TaskVersion }}</code> {{ .TaskDescription }}\nby {{ .Author }}\n{{ .TaskURL }}"}`
 
 // Alert represents an alert that will be templated and sent to the appropriate service
@@ -35,8 +41,6 @@ func (t *TaskRunner) sendMailAlert() {
 		return
 	}
 
-	mailHost := util.Config.EmailHost + ":" + util.Config.EmailPort
-
 	var mailBuffer bytes.Buffer
 	alert := Alert{
 		TaskID:  strconv.Itoa(t.task.ID),
@@ -58,15 +62,72 @@ func (t *TaskRunner) sendMailAlert() {
 		t.panicOnError(err, "Can't find user Email!")
 
 		t.Log("Sending email to " + userObj.Email + " from " + util.Config.EmailSender)
-		if util.Config.EmailSecure {
-			err = util.SendSecureMail(util.Config.EmailHost, util.Config.EmailPort, util.Config.EmailSender, util.Config.EmailUsername, util.Config.EmailPassword, userObj.Email, mailBuffer)
-		} else {
-			err = util.SendMail(mailHost, util.Config.EmailSender, userObj.Email, mailBuffer)
-		}
+		err = sendMail(userObj.Email, mailBuffer)
 		t.panicOnError(err, "Can't send email!")
 	}
 }
 
+func sendMail(email string, body bytes.Buffer) error {
+	if util.Config.EmailSecure {
+		return util.SendSecureMail(util.Config.EmailHost, util.Config.EmailPort, util.Config.EmailSender, util.Config.EmailUsername, util.Config.EmailPassword, email, body)
+	}
+
+	return util.SendMail(util.Config.EmailHost+":"+util.Config.EmailPort, util.Config.EmailSender, email, body)
+}
+
+// sendApprovalRequest sends emails to approvers of the task except the user who started it.
+func (t *TaskRunner) sendApprovalRequest() {
+	if !util.Config.EmailAlert {
+		return
+	}
+
+	var author string
+	if t.task.UserID != nil {
+		author = t.pool.getUserName(*t.task.UserID)
+	}
+
+	alert := Alert{
+		TaskID:  strconv.Itoa(t.task.ID),
+		Name:    t.template.Name,
+		TaskURL: util.Config.WebHost + "/project/" + strconv.Itoa(t.template.ProjectID) + "/templates/" + strconv.Itoa(t.template.ID) + "?t=" + strconv.Itoa(t.task.ID),
+		Author:  author,
+	}
+
+	var mailBuffer bytes.Buffer
+
+	tpl, err := template.New("approval mail body template").Parse(approvalEmailTemplate)
+	if err == nil {
+		err = tpl.Execute(&mailBuffer, alert)
+	}
+	if err != nil {
+		t.Log("Can't generate approval request template!")
+		return
+	}
+
+	users, err := t.pool.store.GetProjectUsers(t.template.ProjectID, db.RetrieveQueryParams{})
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	for _, user := range users {
+		if !user.Alert || (t.task.UserID != nil && *t.task.UserID == user.ID) {
+			continue
+		}
+
+		projectUser, err := t.pool.store.GetProjectUser(t.template.ProjectID, user.ID)
+		if err != nil || !t.template.IsApprover(projectUser) {
+			continue
+		}
+
+		t.Log("Sending approval request to " + user.Email)
+		if err = sendMail(user.Email, mailBuffer); err != nil {
+			t.Log("Can't send approval request to " + user.Email)
+			log.Error(err)
+		}
+	}
+}
+
 func (t *TaskRunner) sendTelegramAlert() {
 	if !util.Config.TelegramAlert || !t.alert {
 		return
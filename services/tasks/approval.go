@@ -0,0 +1,109 @@
+package tasks
+
+import (
+	"strconv"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/ansible-semaphore/semaphore/db"
+)
+
+// holdForApproval keeps the new task out of the queue until an approver approves or rejects it.
+func (p *TaskPool) holdForApproval(t *TaskRunner) {
+	p.awaitingLock.Lock()
+	p.awaitingApproval[t.task.ID] = t
+	p.awaitingLock.Unlock()
+
+	msg := "Task " + strconv.Itoa(t.task.ID) + " awaits approval"
+	t.Log(msg)
+	log.Info(msg)
+	t.updateStatus()
+
+	p.createApprovalEvent(t, t.task.UserID, "awaits approval", "")
+
+	go t.sendApprovalRequest()
+}
+
+// takeAwaitingTask removes the task from tasks awaiting approval.
+// It returns nil if the task does not await approval.
+func (p *TaskPool) takeAwaitingTask(taskID int) *TaskRunner {
+	p.awaitingLock.Lock()
+	defer p.awaitingLock.Unlock()
+
+	t, ok := p.awaitingApproval[taskID]
+	if !ok {
+		return nil
+	}
+
+	delete(p.awaitingApproval, taskID)
+	return t
+}
+
+// ApproveTask puts the task awaiting approval to the queue.
+// The caller must check that the user is an approver of the task template.
+func (p *TaskPool) ApproveTask(task db.Task, userID int, reason string) error {
+	t := p.takeAwaitingTask(task.ID)
+	if t == nil {
+		return db.ErrInvalidOperation
+	}
+
+	t.Log("Task approved by " + p.getUserName(userID) + formatApprovalReason(reason))
+	p.createApprovalEvent(t, &userID, "approved", reason)
+
+	t.task.Status = db.TaskWaitingStatus
+	p.register <- t
+
+	return nil
+}
+
+// RejectTask stops the task awaiting approval.
+// The caller must check that the user is an approver of the task template.
+func (p *TaskPool) RejectTask(task db.Task, userID int, reason string) error {
+	t := p.takeAwaitingTask(task.ID)
+	if t == nil {
+		return db.ErrInvalidOperation
+	}
+
+	t.Log("Task rejected by " + p.getUserName(userID) + formatApprovalReason(reason))
+	p.createApprovalEvent(t, &userID, "rejected", reason)
+
+	now := time.Now()
+	t.task.End = &now
+	t.setStatus(db.TaskStoppedStatus)
+
+	p.onTaskFinished(t.task)
+
+	return nil
+}
+
+func (p *TaskPool) getUserName(userID int) string {
+	user, err := p.store.GetUser(userID)
+	if err != nil {
+		return "user " + strconv.Itoa(userID)
+	}
+	return user.Name
+}
+
+func formatApprovalReason(reason string) string {
+	if reason == "" {
+		return ""
+	}
+	return ": " + reason
+}
+
+func (p *TaskPool) createApprovalEvent(t *TaskRunner, userID *int, decision string, reason string) {
+	objType := db.EventTask
+	desc := "Task ID " + strconv.Itoa(t.task.ID) + " (" + t.template.Name + ") " + decision + formatApprovalReason(reason)
+
+	_, err := p.store.CreateEvent(db.Event{
+		UserID:      userID,
+		ProjectID:   &t.task.ProjectID,
+		ObjectType:  &objType,
+		ObjectID:    &t.task.ID,
+		Description: &desc,
+	})
+
+	if err != nil {
+		log.Error(err)
+	}
+}
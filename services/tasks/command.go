@@ -0,0 +1,153 @@
+package tasks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ansible-semaphore/semaphore/db"
+)
+
+// runScript runs the script or the command of the command template.
+// The script gets the same environment, keys and task details as a playbook,
+// but through environment variables instead of ansible arguments.
+func (t *LocalJob) runScript() (err error) {
+	command, args, err := t.getScriptArgs()
+	if err != nil {
+		return
+	}
+
+	environmentVariables, err := t.getEnvironmentENV()
+	if err != nil {
+		return
+	}
+
+	scriptVariables, err := t.getScriptENV()
+	if err != nil {
+		return
+	}
+
+	environmentVariables = append(environmentVariables, scriptVariables...)
+
+	defer t.collectArtifacts()
+	defer t.collectVersion()
+
+	return t.getAnsiblePlaybook().RunScript(command, args, &environmentVariables)
+}
+
+// getScriptArgs returns the script or the command of the template and its arguments.
+// Scripts are looked up in the repository, other commands in PATH.
+// The task can only choose another script of the repository.
+func (t *LocalJob) getScriptArgs() (command string, args []string, err error) {
+	if t.Task.Playbook != "" {
+		command, err = t.getRepositoryScript(t.Task.Playbook)
+		if err != nil {
+			t.Logger.Log(err.Error())
+			return
+		}
+	} else {
+		command = t.Template.Playbook
+
+		script := filepath.Join(t.getRepoPath(), command)
+		if _, statErr := os.Stat(script); statErr == nil {
+			command = script
+		}
+	}
+
+	if t.Template.Arguments != nil {
+		err = json.Unmarshal([]byte(*t.Template.Arguments), &args)
+		if err != nil {
+			t.Logger.Log("Invalid format of the template extra arguments, must be valid JSON")
+			return
+		}
+	}
+
+	if t.Template.AllowOverrideArgsInTask && t.Task.Arguments != nil {
+		var taskArgs []string
+		err = json.Unmarshal([]byte(*t.Task.Arguments), &taskArgs)
+		if err != nil {
+			t.Logger.Log("Invalid format of the TaskRunner extra arguments, must be valid JSON")
+			return
+		}
+		args = append(args, taskArgs...)
+	}
+
+	return
+}
+
+// getRepositoryScript returns the path of the script of the repository.
+// It fails if the script does not exist or is outside of the repository.
+func (t *LocalJob) getRepositoryScript(name string) (string, error) {
+	repoPath, err := filepath.EvalSymlinks(t.getRepoPath())
+	if err != nil {
+		return "", err
+	}
+
+	script, err := filepath.EvalSymlinks(filepath.Join(repoPath, name))
+	if err != nil {
+		return "", fmt.Errorf("script %s not found in the repository", name)
+	}
+
+	rel, err := filepath.Rel(repoPath, script)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("script %s is outside of the repository", name)
+	}
+
+	return filepath.Join(t.getRepoPath(), name), nil
+}
+
+// getScriptENV returns environment variables which pass inventory, keys and
+// parameters of the task to the script.
+func (t *LocalJob) getScriptENV() (env []string, err error) {
+	inventory, err := t.getInventoryPath()
+	if err != nil {
+		return
+	}
+
+	extraVars, err := t.getEnvironmentExtraVars(true)
+	if err != nil {
+		return
+	}
+
+	env = append(env,
+		"SEMAPHORE_TASK_ID="+strconv.Itoa(t.Task.ID),
+		"SEMAPHORE_INVENTORY="+inventory,
+		"SEMAPHORE_EXTRA_VARS="+extraVars)
+
+	if t.Task.Limit != "" {
+		env = append(env, "SEMAPHORE_LIMIT="+t.Task.Limit)
+	}
+
+	if t.Task.DryRun {
+		env = append(env, "SEMAPHORE_DRY_RUN=1")
+	}
+
+	if t.Task.Debug {
+		env = append(env, "SEMAPHORE_DEBUG=1")
+	}
+
+	if t.Inventory.SSHKeyID != nil {
+		switch t.Inventory.SSHKey.Type {
+		case db.AccessKeySSH:
+			env = append(env, "SEMAPHORE_SSH_KEY_FILE="+t.Inventory.SSHKey.GetPath())
+			if t.Inventory.SSHKey.SshKey.Login != "" {
+				env = append(env, "SEMAPHORE_SSH_LOGIN="+t.Inventory.SSHKey.SshKey.Login)
+			}
+		case db.AccessKeyLoginPassword:
+			env = append(env, "SEMAPHORE_CREDENTIALS_FILE="+t.Inventory.SSHKey.GetPath())
+		}
+	}
+
+	if t.Inventory.BecomeKeyID != nil && t.Inventory.BecomeKey.Type == db.AccessKeyLoginPassword {
+		env = append(env, "SEMAPHORE_BECOME_CREDENTIALS_FILE="+t.Inventory.BecomeKey.GetPath())
+	}
+
+	if t.Template.VaultKeyID != nil {
+		env = append(env, "SEMAPHORE_VAULT_PASSWORD_FILE="+t.Template.VaultKey.GetPath())
+	}
+
+	return
+}
@@ -0,0 +1,166 @@
+package tasks
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/ansible-semaphore/semaphore/db"
+	"github.com/ansible-semaphore/semaphore/util"
+)
+
+func TestLocalJobGetScriptArgs(t *testing.T) {
+	repoPath, err := ioutil.TempDir("", "semaphore_command_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(repoPath) //nolint: errcheck
+
+	err = ioutil.WriteFile(path.Join(repoPath, "deploy.sh"), []byte("#!/bin/sh\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args := "[\"--env\", \"prod\"]"
+	taskArgs := "[\"--force\"]"
+
+	tsk := LocalJob{
+		Task: db.Task{
+			Arguments: &taskArgs,
+		},
+		Template: db.Template{
+			App:                     db.TemplateCommand,
+			Playbook:                "deploy.sh",
+			Arguments:               &args,
+			AllowOverrideArgsInTask: true,
+		},
+		Repository: db.Repository{
+			GitURL: repoPath,
+		},
+	}
+
+	command, cmdArgs, err := tsk.getScriptArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if command != path.Join(repoPath, "deploy.sh") {
+		t.Fatal("script must be run from the repository", command)
+	}
+
+	if strings.Join(cmdArgs, " ") != "--env prod --force" {
+		t.Fatal("incorrect arguments", cmdArgs)
+	}
+
+	tsk.Template.Playbook = "terraform"
+
+	command, _, err = tsk.getScriptArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if command != "terraform" {
+		t.Fatal("commands which are not in the repository must be looked up in PATH", command)
+	}
+}
+
+func TestLocalJobGetScriptArgsTaskOverride(t *testing.T) {
+	repoPath, err := ioutil.TempDir("", "semaphore_command_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(repoPath) //nolint: errcheck
+
+	err = ioutil.WriteFile(path.Join(repoPath, "rollback.sh"), []byte("#!/bin/sh\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Symlink("/bin/sh", path.Join(repoPath, "shell"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tsk := LocalJob{
+		Logger: &testJobLogger{},
+		Task: db.Task{
+			Playbook: "rollback.sh",
+		},
+		Template: db.Template{
+			App:      db.TemplateCommand,
+			Playbook: "deploy.sh",
+		},
+		Repository: db.Repository{
+			GitURL: repoPath,
+		},
+	}
+
+	command, _, err := tsk.getScriptArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if command != path.Join(repoPath, "rollback.sh") {
+		t.Fatal("task can run another script of the repository", command)
+	}
+
+	for _, playbook := range []string{"sh", "/bin/sh", "../../bin/sh", "shell"} {
+		tsk.Task.Playbook = playbook
+		if _, _, err = tsk.getScriptArgs(); err == nil {
+			t.Fatal("task must not run commands outside of the repository: " + playbook)
+		}
+	}
+}
+
+func TestLocalJobGetScriptENV(t *testing.T) {
+	util.Config = &util.ConfigType{
+		TmpPath: "/tmp",
+	}
+
+	keyID := 1
+
+	tsk := LocalJob{
+		Task: db.Task{
+			ID:     5,
+			Limit:  "web",
+			DryRun: true,
+		},
+		Inventory: db.Inventory{
+			SSHKeyID: &keyID,
+			SSHKey: db.AccessKey{
+				ID:   12345,
+				Type: db.AccessKeySSH,
+				SshKey: db.SshKey{
+					Login: "deploy",
+				},
+			},
+			Type: db.InventoryStatic,
+		},
+		Template: db.Template{
+			App:      db.TemplateCommand,
+			Playbook: "deploy.sh",
+		},
+	}
+
+	env, err := tsk.getScriptENV()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := strings.Join(env, "\n")
+
+	for _, v := range []string{
+		"SEMAPHORE_TASK_ID=5",
+		"SEMAPHORE_INVENTORY=/tmp/inventory_5",
+		"SEMAPHORE_LIMIT=web",
+		"SEMAPHORE_DRY_RUN=1",
+		"SEMAPHORE_SSH_KEY_FILE=/tmp/access_key_0",
+		"SEMAPHORE_SSH_LOGIN=deploy",
+	} {
+		if !strings.Contains(res, v+"\n") && !strings.HasSuffix(res, v) {
+			t.Fatal("missing environment variable", v)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package tasks
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ansible-semaphore/semaphore/lib"
+	"github.com/ansible-semaphore/semaphore/util"
+)
+
+// galaxyRequirementsKind is the kind of requirements installed by ansible-galaxy.
+// It is also the directory of requirements.yml in the repository.
+type galaxyRequirementsKind string
+
+const (
+	galaxyCollections galaxyRequirementsKind = "collections"
+	galaxyRoles       galaxyRequirementsKind = "roles"
+)
+
+// defaultGalaxyPaths are default search paths of ansible. They are kept after the cache
+// if the project does not configure search paths, so roles and collections installed
+// on the host are still found.
+var defaultGalaxyPaths = map[galaxyRequirementsKind]string{
+	galaxyCollections: "~/.ansible/collections:/usr/share/ansible/collections",
+	galaxyRoles:       "~/.ansible/roles:/usr/share/ansible/roles:/etc/ansible/roles",
+}
+
+// galaxyCacheLocks serializes installation of the same requirements by parallel tasks.
+var galaxyCacheLocks sync.Map
+
+// getGalaxyCacheDir returns the directory of the cache where requirements with the content of
+// requirementsFile are installed for the runtime, i.e. the executor image and the ansible version.
+// Templates with the same requirements and runtime share the directory.
+// Requirements which are not pinned to versions are not updated until the file changes.
+func getGalaxyCacheDir(kind galaxyRequirementsKind, requirementsFile string, runtime string) (string, error) {
+	content, err := ioutil.ReadFile(requirementsFile)
+	if err != nil {
+		return "", err
+	}
+
+	key := sha256.New()
+	key.Write([]byte(runtime + "\n")) //nolint: errcheck
+	key.Write(content)                //nolint: errcheck
+
+	return path.Join(util.Config.GalaxyCache.Path, string(kind), fmt.Sprintf("%x", key.Sum(nil))), nil
+}
+
+func lockGalaxyCacheDir(dir string) func() {
+	l, _ := galaxyCacheLocks.LoadOrStore(dir, &sync.Mutex{})
+	mutex := l.(*sync.Mutex)
+	mutex.Lock()
+	return mutex.Unlock
+}
+
+// prepareGalaxyCache creates the cache directory and removes requirements which
+// were not used by tasks for longer than the maximum age of the cache.
+func prepareGalaxyCache() error {
+	if err := os.MkdirAll(util.Config.GalaxyCache.Path, 0755); err != nil {
+		return err
+	}
+
+	// zero maximum age disables removal
+	if util.Config.GalaxyCache.MaxAgeDays == nil || *util.Config.GalaxyCache.MaxAgeDays <= 0 {
+		return nil
+	}
+
+	maxAge := time.Duration(*util.Config.GalaxyCache.MaxAgeDays) * 24 * time.Hour
+
+	for _, kind := range []galaxyRequirementsKind{galaxyCollections, galaxyRoles} {
+		kindDir := path.Join(util.Config.GalaxyCache.Path, string(kind))
+
+		entries, err := ioutil.ReadDir(kindDir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			if time.Since(entry.ModTime()) < maxAge {
+				continue
+			}
+
+			if err = removeGalaxyCacheDir(path.Join(kindDir, entry.Name()), maxAge); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// removeGalaxyCacheDir removes the directory unless a task used it after it was found expired.
+func removeGalaxyCacheDir(dir string, maxAge time.Duration) error {
+	unlock := lockGalaxyCacheDir(dir)
+	defer unlock()
+
+	info, err := os.Stat(dir)
+	if err != nil || time.Since(info.ModTime()) < maxAge {
+		return nil
+	}
+
+	return os.RemoveAll(dir)
+}
+
+// getGalaxyRuntime returns the executor image and the ansible version which are a part of
+// the cache key, because collections installed by one ansible may not work with another one.
+func (t *LocalJob) getGalaxyRuntime() (string, error) {
+	if t.galaxyRuntime != "" {
+		return t.galaxyRuntime, nil
+	}
+
+	version, err := t.getAnsiblePlaybook().GetGalaxyVersion()
+	if err != nil {
+		return "", fmt.Errorf("failed to get ansible version: %w", err)
+	}
+
+	image := t.Template.ContainerImage
+	if image == "" {
+		image = "local"
+	}
+
+	t.galaxyRuntime = image + "\n" + version
+	return t.galaxyRuntime, nil
+}
+
+// installGalaxyRequirements installs requirements of the repository to the cache
+// unless they are already there. In offline mode missing requirements fail the task.
+func (t *LocalJob) installGalaxyRequirements(kind galaxyRequirementsKind) error {
+	requirementsFilePath := path.Join(t.getRepoPath(), string(kind), "requirements.yml")
+
+	if _, err := os.Stat(requirementsFilePath); err != nil {
+		t.Logger.Log("No " + string(kind) + "/requirements.yml file found. Skip galaxy install process.\n")
+		return nil
+	}
+
+	runtime, err := t.getGalaxyRuntime()
+	if err != nil {
+		return err
+	}
+
+	cacheDir, err := getGalaxyCacheDir(kind, requirementsFilePath, runtime)
+	if err != nil {
+		return err
+	}
+
+	unlock := lockGalaxyCacheDir(cacheDir)
+	defer unlock()
+
+	if t.galaxyCacheDirs == nil {
+		t.galaxyCacheDirs = make(map[galaxyRequirementsKind]string)
+	}
+
+	if _, err = os.Stat(cacheDir); err == nil {
+		t.Logger.Log(string(kind) + "/requirements.yml found in galaxy cache. Skip galaxy install process.\n")
+		t.galaxyCacheDirs[kind] = cacheDir
+
+		// the time of the last use protects the requirements from removal
+		now := time.Now()
+		return os.Chtimes(cacheDir, now, now)
+	}
+
+	if util.Config.GalaxyCache.Offline {
+		return fmt.Errorf("%s/requirements.yml is not in galaxy cache and offline mode is enabled", kind)
+	}
+
+	// requirements are installed to a temporary directory, so other tasks never see partial installs
+	tmpDir := cacheDir + "_tmp_" + strconv.Itoa(t.Task.ID)
+
+	if err = os.RemoveAll(tmpDir); err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir) //nolint: errcheck
+
+	if err = os.MkdirAll(tmpDir, 0755); err != nil {
+		return err
+	}
+
+	err = t.runGalaxy([]string{
+		strings.TrimSuffix(string(kind), "s"),
+		"install",
+		"-r",
+		requirementsFilePath,
+		"-p",
+		tmpDir,
+		"--force",
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmpDir, cacheDir); err != nil {
+		// the requirements could be installed by another process
+		if _, statErr := os.Stat(cacheDir); statErr != nil {
+			return err
+		}
+	}
+
+	t.galaxyCacheDirs[kind] = cacheDir
+	return nil
+}
+
+// getGalaxyCacheENV returns environment variables which make ansible find
+// requirements of the task in the cache before search paths configured by the project.
+func (t *LocalJob) getGalaxyCacheENV(settings lib.AnsibleSettings) (env []string) {
+	if dir, ok := t.galaxyCacheDirs[galaxyCollections]; ok {
+		paths, found := settings.Get("ANSIBLE_COLLECTIONS_PATH", "collections_path")
+		if !found {
+			// old names of the setting
+			paths, found = settings.Get("ANSIBLE_COLLECTIONS_PATHS", "collections_paths")
+		}
+		if !found {
+			paths = defaultGalaxyPaths[galaxyCollections]
+		}
+		env = append(env, "ANSIBLE_COLLECTIONS_PATH="+dir+":"+paths)
+	}
+
+	if dir, ok := t.galaxyCacheDirs[galaxyRoles]; ok {
+		paths, found := settings.Get("ANSIBLE_ROLES_PATH", "roles_path")
+		if !found {
+			paths = defaultGalaxyPaths[galaxyRoles]
+		}
+		env = append(env, "ANSIBLE_ROLES_PATH="+dir+":"+paths)
+	}
+
+	return
+}
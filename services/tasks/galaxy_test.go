@@ -0,0 +1,224 @@
+package tasks
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ansible-semaphore/semaphore/db"
+	"github.com/ansible-semaphore/semaphore/lib"
+	"github.com/ansible-semaphore/semaphore/util"
+)
+
+// testJobLogger records messages of the job. Other methods must not be called by tests.
+type testJobLogger struct {
+	JobLogger
+	messages []string
+	version  *string
+}
+
+func (l *testJobLogger) Log(msg string) {
+	l.messages = append(l.messages, msg)
+}
+
+func (l *testJobLogger) SetVersion(version string) {
+	l.version = &version
+}
+
+func createGalaxyTestRepo(t *testing.T, requirements string) string {
+	repoPath, err := ioutil.TempDir("", "semaphore_galaxy_repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.MkdirAll(path.Join(repoPath, "roles"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	err = ioutil.WriteFile(path.Join(repoPath, "roles", "requirements.yml"), []byte(requirements), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return repoPath
+}
+
+const testGalaxyRuntime = "local\nansible-galaxy [core 2.15.0]"
+
+func TestGetGalaxyCacheDir(t *testing.T) {
+	oldConfig := util.Config
+	util.Config = &util.ConfigType{
+		GalaxyCache: util.GalaxyCacheSettings{Path: "/tmp/galaxy_cache"},
+	}
+	defer func() { util.Config = oldConfig }()
+
+	repo1 := createGalaxyTestRepo(t, "- src: geerlingguy.docker\n  version: 6.1.0\n")
+	defer os.RemoveAll(repo1) //nolint: errcheck
+	repo2 := createGalaxyTestRepo(t, "- src: geerlingguy.docker\n  version: 6.1.0\n")
+	defer os.RemoveAll(repo2) //nolint: errcheck
+	repo3 := createGalaxyTestRepo(t, "- src: geerlingguy.docker\n  version: 7.0.0\n")
+	defer os.RemoveAll(repo3) //nolint: errcheck
+
+	dir1, err := getGalaxyCacheDir(galaxyRoles, path.Join(repo1, "roles", "requirements.yml"), testGalaxyRuntime)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir2, err := getGalaxyCacheDir(galaxyRoles, path.Join(repo2, "roles", "requirements.yml"), testGalaxyRuntime)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir3, err := getGalaxyCacheDir(galaxyRoles, path.Join(repo3, "roles", "requirements.yml"), testGalaxyRuntime)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dir1 != dir2 {
+		t.Fatal("requirements with the same content must share the cache")
+	}
+
+	if dir1 == dir3 {
+		t.Fatal("requirements with different content must not share the cache")
+	}
+
+	dir4, err := getGalaxyCacheDir(galaxyRoles, path.Join(repo1, "roles", "requirements.yml"), "geerlingguy/docker-ubuntu2204-ansible\nansible-galaxy [core 2.16.0]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dir1 == dir4 {
+		t.Fatal("requirements installed by different runtimes must not share the cache")
+	}
+
+	if !strings.HasPrefix(dir1, "/tmp/galaxy_cache/roles/") {
+		t.Fatal("unexpected cache directory", dir1)
+	}
+}
+
+func TestInstallGalaxyRequirementsOffline(t *testing.T) {
+	cachePath, err := ioutil.TempDir("", "semaphore_galaxy_cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cachePath) //nolint: errcheck
+
+	oldConfig := util.Config
+	util.Config = &util.ConfigType{
+		GalaxyCache: util.GalaxyCacheSettings{Path: cachePath, Offline: true},
+	}
+	defer func() { util.Config = oldConfig }()
+
+	repoPath := createGalaxyTestRepo(t, "- src: geerlingguy.docker\n")
+	defer os.RemoveAll(repoPath) //nolint: errcheck
+
+	tsk := LocalJob{
+		Logger:        &testJobLogger{},
+		Repository:    db.Repository{GitURL: repoPath},
+		galaxyRuntime: testGalaxyRuntime,
+	}
+
+	if err = tsk.installGalaxyRequirements(galaxyRoles); err == nil {
+		t.Fatal("missing requirements must fail in offline mode")
+	}
+
+	cacheDir, err := getGalaxyCacheDir(galaxyRoles, path.Join(repoPath, "roles", "requirements.yml"), testGalaxyRuntime)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.MkdirAll(cacheDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tsk.installGalaxyRequirements(galaxyRoles); err != nil {
+		t.Fatal(err)
+	}
+
+	env := strings.Join(tsk.getGalaxyCacheENV(lib.AnsibleSettings{}), "\n")
+	if !strings.Contains(env, "ANSIBLE_ROLES_PATH="+cacheDir+":"+defaultGalaxyPaths[galaxyRoles]) {
+		t.Fatal("roles must be resolved from the cache", env)
+	}
+
+	if strings.Contains(env, "ANSIBLE_COLLECTIONS_PATH") {
+		t.Fatal("collections path must not be changed without collection requirements")
+	}
+
+	err = ioutil.WriteFile(path.Join(repoPath, "ansible.cfg"), []byte("[defaults]\nroles_path = roles:shared_roles\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	settings, err := lib.ReadAnsibleSettings(repoPath, map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env = strings.Join(tsk.getGalaxyCacheENV(settings), "\n")
+	if env != "ANSIBLE_ROLES_PATH="+cacheDir+":"+path.Join(repoPath, "roles")+":"+path.Join(repoPath, "shared_roles") {
+		t.Fatal("roles path of ansible.cfg must be searched after the cache", env)
+	}
+}
+
+func TestPrepareGalaxyCache(t *testing.T) {
+	cachePath, err := ioutil.TempDir("", "semaphore_galaxy_cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cachePath) //nolint: errcheck
+
+	maxAgeDays := 30
+
+	oldConfig := util.Config
+	util.Config = &util.ConfigType{
+		GalaxyCache: util.GalaxyCacheSettings{Path: cachePath, MaxAgeDays: &maxAgeDays},
+	}
+	defer func() { util.Config = oldConfig }()
+
+	used := path.Join(cachePath, string(galaxyRoles), "used")
+	unused := path.Join(cachePath, string(galaxyCollections), "unused")
+
+	for _, dir := range []string{used, unused} {
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	old := time.Now().AddDate(0, 0, -31)
+	if err = os.Chtimes(unused, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = prepareGalaxyCache(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = os.Stat(used); err != nil {
+		t.Fatal("recently used requirements must be kept")
+	}
+
+	if _, err = os.Stat(unused); !os.IsNotExist(err) {
+		t.Fatal("requirements not used for longer than the maximum age must be removed")
+	}
+
+	if err = os.MkdirAll(unused, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.Chtimes(unused, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	maxAgeDays = 0
+
+	if err = prepareGalaxyCache(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = os.Stat(unused); err != nil {
+		t.Fatal("requirements must be kept if removal is disabled")
+	}
+}
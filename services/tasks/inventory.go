@@ -1,6 +1,7 @@
 package tasks
 
 import (
+	"fmt"
 	"github.com/ansible-semaphore/semaphore/db"
 	"io/ioutil"
 	"strconv"
@@ -8,36 +9,51 @@ import (
 	"github.com/ansible-semaphore/semaphore/util"
 )
 
-func (t *TaskRunner) installInventory() (err error) {
-	if t.inventory.SSHKeyID != nil {
-		err = t.inventory.SSHKey.Install(db.AccessKeyRoleAnsibleUser)
+func (t *LocalJob) installInventory() (err error) {
+	if t.Inventory.SSHKeyID != nil {
+		err = t.Inventory.SSHKey.Install(db.AccessKeyRoleAnsibleUser)
 		if err != nil {
 			return
 		}
 	}
 
-	if t.inventory.BecomeKeyID != nil {
-		err = t.inventory.BecomeKey.Install(db.AccessKeyRoleAnsibleBecomeUser)
+	if t.Inventory.BecomeKeyID != nil {
+		err = t.Inventory.BecomeKey.Install(db.AccessKeyRoleAnsibleBecomeUser)
 		if err != nil {
 			return
 		}
 	}
 
-	if t.inventory.Type == db.InventoryStatic || t.inventory.Type == db.InventoryStaticYaml {
+	if t.Inventory.Type == db.InventoryStatic || t.Inventory.Type == db.InventoryStaticYaml {
 		err = t.installStaticInventory()
 	}
 
 	return
 }
 
-func (t *TaskRunner) installStaticInventory() error {
-	t.Log("installing static inventory")
-
-	path := util.Config.TmpPath + "/inventory_" + strconv.Itoa(t.task.ID)
-	if t.inventory.Type == db.InventoryStaticYaml {
-		path += ".yml"
+// getInventoryPath returns the inventory passed to ansible.
+func (t *LocalJob) getInventoryPath() (string, error) {
+	switch t.Inventory.Type {
+	case db.InventoryFile:
+		return t.Inventory.Inventory, nil
+	case db.InventoryStatic, db.InventoryStaticYaml:
+		return t.getStaticInventoryPath(), nil
+	default:
+		return "", fmt.Errorf("invalid invetory type")
 	}
+}
+
+func (t *LocalJob) installStaticInventory() error {
+	t.Logger.Log("installing static inventory")
 
 	// create inventory file
-	return ioutil.WriteFile(path, []byte(t.inventory.Inventory), 0664)
+	return ioutil.WriteFile(t.getStaticInventoryPath(), []byte(t.Inventory.Inventory), 0664)
+}
+
+func (t *LocalJob) getStaticInventoryPath() string {
+	path := util.Config.TmpPath + "/inventory_" + strconv.Itoa(t.Task.ID)
+	if t.Inventory.Type == db.InventoryStaticYaml {
+		path += ".yml"
+	}
+	return path
 }
@@ -0,0 +1,32 @@
+package tasks
+
+import (
+	"io"
+
+	"github.com/ansible-semaphore/semaphore/db"
+	"github.com/ansible-semaphore/semaphore/lib"
+)
+
+// Job executes a task which was populated by TaskRunner.
+// Run blocks until the task is finished.
+type Job interface {
+	Run(username string, incomingVersion *string) error
+	Kill()
+}
+
+// JobLogger receives output, repository state, host results and artifacts produced by a Job.
+type JobLogger interface {
+	lib.Logger
+	SetCommit(hash string, message string)
+	SetHostResults(hosts []db.TaskHost)
+	// SetDiffs stores changes reported by the playbook run with --diff.
+	SetDiffs(diffs []db.TaskDiff)
+	// SetAnnotations stores problems found by pre-flight validation of the playbook.
+	SetAnnotations(annotations []db.TaskAnnotation)
+	// SetVersion stores the version which the build task actually built.
+	SetVersion(version string)
+	// AddArtifact stores a file produced by the job.
+	AddArtifact(name string, content io.Reader) error
+	// GetBuildArtifact returns content of an artifact of the build task.
+	GetBuildArtifact(artifact db.TaskArtifact) (io.ReadCloser, error)
+}
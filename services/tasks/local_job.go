@@ -0,0 +1,816 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ansible-semaphore/semaphore/db"
+	"github.com/ansible-semaphore/semaphore/lib"
+	"github.com/ansible-semaphore/semaphore/util"
+)
+
+// LocalJob runs a task as a child process of the current process.
+// It is used by the server and by remote runners.
+type LocalJob struct {
+	Task        db.Task
+	Template    db.Template
+	Inventory   db.Inventory
+	Repository  db.Repository
+	Environment db.Environment
+	Logger      JobLogger
+
+	// BuildArtifacts are files produced by the build task of the deploy task.
+	// They are put to the directory passed to the playbook as build_artifacts_dir.
+	BuildArtifacts []db.TaskArtifact
+
+	username        string
+	incomingVersion *string
+
+	// galaxyCacheDirs are directories of the galaxy cache with requirements of the task.
+	galaxyCacheDirs map[galaxyRequirementsKind]string
+	// galaxyRuntime identifies the image and the ansible version which install requirements.
+	galaxyRuntime string
+
+	// ctx is cancelled when the job is killed. It stops all commands of the job
+	// including preparation steps like git and ansible-galaxy.
+	ctx      context.Context
+	cancel   context.CancelFunc
+	ctxMutex sync.Mutex
+}
+
+func (t *LocalJob) getContext() context.Context {
+	t.ctxMutex.Lock()
+	defer t.ctxMutex.Unlock()
+
+	if t.ctx == nil {
+		t.ctx, t.cancel = context.WithCancel(context.Background())
+	}
+
+	return t.ctx
+}
+
+func (t *LocalJob) Kill() {
+	t.getContext()
+	t.cancel()
+}
+
+func (t *LocalJob) isKilled() bool {
+	return t.getContext().Err() != nil
+}
+
+func (t *LocalJob) Run(username string, incomingVersion *string) (err error) {
+	t.username = username
+	t.incomingVersion = incomingVersion
+
+	defer t.destroyKeys()
+	defer t.destroyWorkspace()
+	defer t.destroyCallbackDir()
+	defer t.destroyContainerHomeDir()
+	defer t.destroyBuildArtifacts()
+
+	err = t.prepareRun()
+
+	if t.isKilled() {
+		err = fmt.Errorf("task stopped")
+		return
+	}
+
+	if err != nil {
+		return
+	}
+
+	err = t.validatePlaybook()
+	if err != nil {
+		return
+	}
+
+	if t.Task.ValidateOnly {
+		t.Logger.Log("Playbook is valid")
+		return
+	}
+
+	return t.runPlaybook()
+}
+
+func (t *LocalJob) getRepoPath() string {
+	return t.getGitRepository().GetFullPath()
+}
+
+func (t *LocalJob) destroyKeys() {
+	err := t.Inventory.SSHKey.Destroy()
+	if err != nil {
+		t.Logger.Log("Can't destroy inventory user key, error: " + err.Error())
+	}
+
+	err = t.Inventory.BecomeKey.Destroy()
+	if err != nil {
+		t.Logger.Log("Can't destroy inventory become user key, error: " + err.Error())
+	}
+
+	err = t.Template.VaultKey.Destroy()
+	if err != nil {
+		t.Logger.Log("Can't destroy inventory vault password file, error: " + err.Error())
+	}
+}
+
+func (t *LocalJob) prepareRun() error {
+	t.Logger.Log("Preparing: " + strconv.Itoa(t.Task.ID))
+
+	if err := checkTmpDir(util.Config.TmpPath); err != nil {
+		t.Logger.Log("Creating tmp dir failed: " + err.Error())
+		return err
+	}
+
+	t.Logger.Log("Prepare TaskRunner with template: " + t.Template.Name + "\n")
+
+	if t.Repository.GetType() == db.RepositoryLocal {
+		if _, err := os.Stat(t.Repository.GitURL); err != nil {
+			t.Logger.Log("Failed in finding static repository at " + t.Repository.GitURL + ": " + err.Error())
+			return err
+		}
+	} else {
+		if err := t.updateRepository(); err != nil {
+			t.Logger.Log("Failed updating repository: " + err.Error())
+			return err
+		}
+		if err := t.checkoutRepository(); err != nil {
+			t.Logger.Log("Failed to checkout repository to required commit: " + err.Error())
+			return err
+		}
+	}
+
+	if err := t.setVersionFromGitTag(); err != nil {
+		t.Logger.Log("Failed to get version from git tags: " + err.Error())
+		return err
+	}
+
+	if err := t.installInventory(); err != nil {
+		t.Logger.Log("Failed to install inventory: " + err.Error())
+		return err
+	}
+
+	// galaxy requirements are used only by playbooks
+	if t.Template.App == db.TemplateAnsible {
+		if err := prepareGalaxyCache(); err != nil {
+			t.Logger.Log("Failed to prepare galaxy cache: " + err.Error())
+			return err
+		}
+
+		if err := t.installRequirements(); err != nil {
+			t.Logger.Log("Running galaxy failed: " + err.Error())
+			return err
+		}
+	}
+
+	if err := t.installVaultKeyFile(); err != nil {
+		t.Logger.Log("Failed to install vault password file: " + err.Error())
+		return err
+	}
+
+	if err := lib.InstallCallbackPlugins(t.getCallbackDir()); err != nil {
+		t.Logger.Log("Failed to install callback plugins: " + err.Error())
+		return err
+	}
+
+	if err := t.installSecretVars(); err != nil {
+		t.Logger.Log("Failed to install secret variables: " + err.Error())
+		return err
+	}
+
+	if err := t.installBuildArtifacts(); err != nil {
+		t.Logger.Log("Failed to install build artifacts: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (t *LocalJob) installVaultKeyFile() error {
+	if t.Template.VaultKeyID == nil {
+		return nil
+	}
+
+	return t.Template.VaultKey.Install(db.AccessKeyRoleAnsiblePasswordVault)
+}
+
+func (t *LocalJob) getGitRepository() lib.GitRepository {
+	return lib.GitRepository{
+		Logger:     t.Logger,
+		TaskID:     t.Task.ID,
+		Repository: t.Repository,
+	}
+}
+
+func (t *LocalJob) checkoutRepository() error {
+	repo := t.getGitRepository()
+	repo.Context = t.getContext()
+
+	if t.Task.CommitHash != nil {
+		// checkout to commit if it is provided for TaskRunner
+		return repo.CreateWorkspace(*t.Task.CommitHash)
+	}
+
+	// store commit to TaskRunner table
+
+	commitHash, err := repo.GetLastCommitHash()
+
+	if err != nil {
+		return err
+	}
+
+	commitMessage, _ := repo.GetCommitMessage(commitHash)
+
+	t.Task.CommitHash = &commitHash
+	t.Task.CommitMessage = commitMessage
+
+	t.Logger.SetCommit(commitHash, commitMessage)
+
+	return repo.CreateWorkspace(commitHash)
+}
+
+// setVersionFromGitTag uses the latest tag of the checked out commit as the version of the build.
+func (t *LocalJob) setVersionFromGitTag() error {
+	if t.Template.Type != db.TemplateBuild || t.Template.VersionStrategy != db.TemplateVersionGitTag {
+		return nil
+	}
+
+	repo := t.getGitRepository()
+	repo.Context = t.getContext()
+
+	tag, err := repo.GetLastTag()
+	if errors.Is(err, lib.ErrNoTags) {
+		// a repository without releases must not break the build
+		t.Logger.Log("Warning: the commit has no tags, so the build has no version")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	t.Task.Version = &tag
+	t.Logger.SetVersion(tag)
+
+	return nil
+}
+
+func (t *LocalJob) updateRepository() error {
+	repo := t.getGitRepository()
+	repo.Context = t.getContext()
+	return repo.UpdateMirror()
+}
+
+// destroyWorkspace removes the working copy of the repository created for the task.
+// It runs git without the job context, so the workspace is removed after the job is killed.
+func (t *LocalJob) destroyWorkspace() {
+	if t.Repository.GetType() == db.RepositoryLocal {
+		return
+	}
+
+	if _, err := os.Stat(t.getRepoPath()); os.IsNotExist(err) {
+		return
+	}
+
+	if err := t.getGitRepository().DestroyWorkspace(); err != nil {
+		t.Logger.Log("Can't remove task workspace, error: " + err.Error())
+	}
+}
+
+func (t *LocalJob) installRequirements() error {
+	if err := t.installGalaxyRequirements(galaxyCollections); err != nil {
+		return err
+	}
+	if err := t.installGalaxyRequirements(galaxyRoles); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *LocalJob) getAnsiblePlaybook() lib.AnsiblePlaybook {
+	return lib.AnsiblePlaybook{
+		Logger:     t.Logger,
+		TaskID:     t.Task.ID,
+		Repository: t.Repository,
+		Executor:   t.getExecutor(),
+		Context:    t.getContext(),
+	}
+}
+
+func (t *LocalJob) runGalaxy(args []string) error {
+	return t.getAnsiblePlaybook().RunGalaxy(args)
+}
+
+func (t *LocalJob) runPlaybook() (err error) {
+	if t.Template.App == db.TemplateCommand {
+		return t.runScript()
+	}
+
+	args, err := t.getPlaybookArgs()
+	if err != nil {
+		return
+	}
+
+	environmentVariables, err := t.getEnvironmentENV()
+	if err != nil {
+		return
+	}
+
+	defer t.collectHostResults()
+	defer t.collectDiffs()
+	defer t.collectArtifacts()
+	defer t.collectVersion()
+
+	return t.getAnsiblePlaybook().RunPlaybook(args, &environmentVariables)
+}
+
+// getExecutor returns the executor which runs ansible for the template.
+func (t *LocalJob) getExecutor() lib.Executor {
+	if t.Template.ContainerImage == "" {
+		return lib.LocalExecutor{}
+	}
+
+	homeDir := t.getContainerHomeDir()
+	if err := os.MkdirAll(homeDir, 0755); err != nil {
+		t.Logger.Log("Failed to create container home directory: " + err.Error())
+	}
+
+	return lib.ContainerExecutor{
+		Engine:    util.Config.ContainerExecutor.Engine,
+		Image:     t.Template.ContainerImage,
+		ExtraArgs: util.Config.ContainerExecutor.Args,
+		Name:      "semaphore-task-" + strconv.Itoa(t.Task.ID),
+		HomeDir:   homeDir,
+		Files:     t.getInstalledFiles(),
+		Dirs:      t.getMountedDirs(),
+	}
+}
+
+// getContainerHomeDir returns the home directory of the container. Every task has its own
+// directory, so tasks of the same template running at the same time do not share ansible state.
+func (t *LocalJob) getContainerHomeDir() string {
+	return path.Join(util.Config.TmpPath, "container_home_"+strconv.Itoa(t.Task.ID))
+}
+
+func (t *LocalJob) destroyContainerHomeDir() {
+	if err := os.RemoveAll(t.getContainerHomeDir()); err != nil {
+		t.Logger.Log("Can't remove container home directory, error: " + err.Error())
+	}
+}
+
+// getMountedDirs returns directories outside of the repository which must be writable by ansible.
+func (t *LocalJob) getMountedDirs() []string {
+	dirs := []string{t.getCallbackDir()}
+
+	// galaxy installs requirements to the cache from inside of the container,
+	// the directory is created by prepareRun
+	if t.Template.App == db.TemplateAnsible {
+		dirs = append(dirs, util.Config.GalaxyCache.Path)
+	}
+
+	if len(t.BuildArtifacts) > 0 {
+		dirs = append(dirs, t.getBuildArtifactsDir())
+	}
+
+	return dirs
+}
+
+// getInstalledFiles returns paths of the inventory and key files installed for the task.
+func (t *LocalJob) getInstalledFiles() (files []string) {
+	if t.Inventory.Type == db.InventoryStatic || t.Inventory.Type == db.InventoryStaticYaml {
+		files = append(files, t.getStaticInventoryPath())
+	}
+
+	keys := []db.AccessKey{t.Inventory.SSHKey, t.Inventory.BecomeKey, t.Template.VaultKey}
+
+	for _, key := range keys {
+		if key.Type == db.AccessKeyNone || key.Type == "" {
+			continue
+		}
+		if _, err := os.Stat(key.GetPath()); err == nil {
+			files = append(files, key.GetPath())
+		}
+	}
+
+	return
+}
+
+// getCallbackDir returns the directory with callback plugins and results reported by them.
+func (t *LocalJob) getCallbackDir() string {
+	return path.Join(util.Config.TmpPath, "callbacks_"+strconv.Itoa(t.Task.ID))
+}
+
+// getSecretVarsPath returns the file with answers to secret survey variables.
+func (t *LocalJob) getSecretVarsPath() string {
+	return path.Join(t.getCallbackDir(), "secret_vars.json")
+}
+
+// installSecretVars writes answers to secret survey variables to the file readable only by the owner.
+// The file is removed together with the callback directory.
+func (t *LocalJob) installSecretVars() error {
+	if len(t.Task.SecretSurveyValues) == 0 {
+		return nil
+	}
+
+	content, err := json.Marshal(t.Task.SecretSurveyValues)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(t.getSecretVarsPath(), content, 0600)
+}
+
+func (t *LocalJob) getHostResultsPath() string {
+	return path.Join(t.getCallbackDir(), "hosts.json")
+}
+
+// collectHostResults passes per-host results written by the callback plugin to the logger.
+func (t *LocalJob) collectHostResults() {
+	content, err := ioutil.ReadFile(t.getHostResultsPath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			t.Logger.Log("Failed to read host results: " + err.Error())
+		}
+		return
+	}
+
+	var hosts []db.TaskHost
+	if err = json.Unmarshal(content, &hosts); err != nil {
+		t.Logger.Log("Failed to parse host results: " + err.Error())
+		return
+	}
+
+	t.Logger.SetHostResults(hosts)
+}
+
+func (t *LocalJob) getDiffsPath() string {
+	return path.Join(t.getCallbackDir(), "diffs.json")
+}
+
+// collectDiffs passes changes written by the callback plugin to the logger.
+func (t *LocalJob) collectDiffs() {
+	if !t.Task.Diff {
+		return
+	}
+
+	content, err := ioutil.ReadFile(t.getDiffsPath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			t.Logger.Log("Failed to read diffs: " + err.Error())
+		}
+		return
+	}
+
+	var diffs []db.TaskDiff
+	if err = json.Unmarshal(content, &diffs); err != nil {
+		t.Logger.Log("Failed to parse diffs: " + err.Error())
+		return
+	}
+
+	if len(diffs) > 0 {
+		t.Logger.SetDiffs(diffs)
+	}
+}
+
+// getVersionFilePath returns the file to which the playbook of the build can write
+// the version it actually built.
+func (t *LocalJob) getVersionFilePath() string {
+	return path.Join(t.getCallbackDir(), "version")
+}
+
+// collectVersion passes the version written by the playbook to the logger.
+func (t *LocalJob) collectVersion() {
+	if t.Template.Type != db.TemplateBuild {
+		return
+	}
+
+	content, err := ioutil.ReadFile(t.getVersionFilePath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			t.Logger.Log("Failed to read build version: " + err.Error())
+		}
+		return
+	}
+
+	version := strings.TrimSpace(string(content))
+	if version == "" || (t.Task.Version != nil && *t.Task.Version == version) {
+		return
+	}
+
+	t.Task.Version = &version
+	t.Logger.SetVersion(version)
+}
+
+func (t *LocalJob) destroyCallbackDir() {
+	if err := os.RemoveAll(t.getCallbackDir()); err != nil {
+		t.Logger.Log("Can't remove callback directory, error: " + err.Error())
+	}
+}
+
+// collectArtifacts passes files matched by the artifacts pattern of the template to the logger.
+func (t *LocalJob) collectArtifacts() {
+	if t.Template.Artifacts == "" {
+		return
+	}
+
+	names, err := lib.FindArtifacts(t.getRepoPath(), t.Template.Artifacts)
+	if err != nil {
+		t.Logger.Log("Failed to find artifacts: " + err.Error())
+		return
+	}
+
+	for _, name := range names {
+		if err = t.addArtifact(name); err != nil {
+			t.Logger.Log("Failed to store artifact " + name + ": " + err.Error())
+		}
+	}
+
+	t.Logger.Log("Artifacts stored: " + strconv.Itoa(len(names)))
+}
+
+func (t *LocalJob) addArtifact(name string) error {
+	file, err := os.Open(filepath.Join(t.getRepoPath(), name))
+	if err != nil {
+		return err
+	}
+
+	defer file.Close() //nolint: errcheck
+
+	return t.Logger.AddArtifact(filepath.ToSlash(name), file)
+}
+
+// getBuildArtifactsDir returns the directory with artifacts of the build task.
+func (t *LocalJob) getBuildArtifactsDir() string {
+	return path.Join(util.Config.TmpPath, "build_artifacts_"+strconv.Itoa(t.Task.ID))
+}
+
+func (t *LocalJob) installBuildArtifacts() error {
+	for _, artifact := range t.BuildArtifacts {
+		if err := t.installBuildArtifact(artifact); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *LocalJob) installBuildArtifact(artifact db.TaskArtifact) error {
+	content, err := t.Logger.GetBuildArtifact(artifact)
+	if err != nil {
+		return err
+	}
+
+	defer content.Close() //nolint: errcheck
+
+	p := filepath.Join(t.getBuildArtifactsDir(), filepath.Clean("/"+artifact.Name))
+
+	if err = os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(file, content)
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func (t *LocalJob) destroyBuildArtifacts() {
+	if len(t.BuildArtifacts) == 0 {
+		return
+	}
+
+	if err := os.RemoveAll(t.getBuildArtifactsDir()); err != nil {
+		t.Logger.Log("Can't remove build artifacts directory, error: " + err.Error())
+	}
+}
+
+func (t *LocalJob) getEnvironmentENV() (arr []string, err error) {
+	environmentVars := make(map[string]string)
+
+	if t.Environment.ENV != nil {
+		err = json.Unmarshal([]byte(*t.Environment.ENV), &environmentVars)
+		if err != nil {
+			return
+		}
+	}
+
+	for key, val := range environmentVars {
+		arr = append(arr, fmt.Sprintf("%s=%s", key, val))
+	}
+
+	settings, err := t.getAnsibleSettings(environmentVars)
+	if err != nil {
+		return
+	}
+
+	// the variables below extend settings of the environment, so they must be set after it
+	arr = append(arr, lib.GetCallbackPluginsEnv(t.getCallbackDir(), settings)...)
+	arr = append(arr, "SEMAPHORE_HOSTS_FILE="+t.getHostResultsPath())
+	arr = append(arr, "SEMAPHORE_DIFFS_FILE="+t.getDiffsPath())
+	arr = append(arr, t.getGalaxyCacheENV(settings)...)
+
+	return
+}
+
+// getAnsibleSettings returns settings of ansible which are set by environment variables
+// of the task or by ansible.cfg of the repository.
+func (t *LocalJob) getAnsibleSettings(environmentVars map[string]string) (lib.AnsibleSettings, error) {
+	env := make(map[string]string)
+
+	// containers do not inherit environment of the server
+	if t.Template.ContainerImage == "" {
+		for _, v := range os.Environ() {
+			pair := strings.SplitN(v, "=", 2)
+			if len(pair) == 2 {
+				env[pair[0]] = pair[1]
+			}
+		}
+	}
+
+	for key, val := range environmentVars {
+		env[key] = val
+	}
+
+	return lib.ReadAnsibleSettings(t.getRepoPath(), env)
+}
+
+// getEnvironmentExtraVars returns variables of the environment and answers to the survey.
+// Answers to secret survey variables are included only if includeSecrets is set.
+func (t *LocalJob) getEnvironmentExtraVars(includeSecrets bool) (str string, err error) {
+	extraVars := make(map[string]interface{})
+
+	if t.Environment.JSON != "" {
+		err = json.Unmarshal([]byte(t.Environment.JSON), &extraVars)
+		if err != nil {
+			return
+		}
+	}
+
+	for name, value := range t.Task.SurveyValues {
+		extraVars[name] = value
+	}
+
+	if includeSecrets {
+		for name, value := range t.Task.SecretSurveyValues {
+			extraVars[name] = value
+		}
+	}
+
+	taskDetails := make(map[string]interface{})
+
+	if t.Task.Message != "" {
+		taskDetails["message"] = t.Task.Message
+	}
+
+	taskDetails["attempt"] = t.Task.GetAttempt()
+
+	if t.username != "" {
+		taskDetails["username"] = t.username
+	}
+
+	if t.Template.Type != db.TemplateTask {
+		taskDetails["type"] = t.Template.Type
+		if t.incomingVersion != nil {
+			taskDetails["incoming_version"] = t.incomingVersion
+		}
+		if t.Template.Type == db.TemplateBuild {
+			taskDetails["target_version"] = t.Task.Version
+			taskDetails["version_file"] = t.getVersionFilePath()
+		}
+		if len(t.BuildArtifacts) > 0 {
+			taskDetails["build_artifacts_dir"] = t.getBuildArtifactsDir()
+		}
+	}
+
+	vars := make(map[string]interface{})
+	vars["task_details"] = taskDetails
+	extraVars["semaphore_vars"] = vars
+
+	ev, err := json.Marshal(extraVars)
+	if err != nil {
+		return
+	}
+
+	str = string(ev)
+
+	return
+}
+
+//nolint: gocyclo
+func (t *LocalJob) getPlaybookArgs() (args []string, err error) {
+	playbookName := t.Task.Playbook
+	if playbookName == "" {
+		playbookName = t.Template.Playbook
+	}
+
+	inventory, err := t.getInventoryPath()
+	if err != nil {
+		return
+	}
+
+	args = []string{
+		"-i", inventory,
+	}
+
+	if t.Inventory.SSHKeyID != nil {
+		switch t.Inventory.SSHKey.Type {
+		case db.AccessKeySSH:
+			args = append(args, "--private-key="+t.Inventory.SSHKey.GetPath())
+			//args = append(args, "--extra-vars={\"ansible_ssh_private_key_file\": \""+t.Inventory.SSHKey.GetPath()+"\"}")
+			if t.Inventory.SSHKey.SshKey.Login != "" {
+				args = append(args, "--extra-vars={\"ansible_user\": \""+t.Inventory.SSHKey.SshKey.Login+"\"}")
+			}
+		case db.AccessKeyLoginPassword:
+			args = append(args, "--extra-vars=@"+t.Inventory.SSHKey.GetPath())
+		case db.AccessKeyNone:
+		default:
+			err = fmt.Errorf("access key does not suite for inventory's user credentials")
+			return
+		}
+	}
+
+	if t.Inventory.BecomeKeyID != nil {
+		switch t.Inventory.BecomeKey.Type {
+		case db.AccessKeyLoginPassword:
+			args = append(args, "--extra-vars=@"+t.Inventory.BecomeKey.GetPath())
+		case db.AccessKeyNone:
+		default:
+			err = fmt.Errorf("access key does not suite for inventory's sudo user credentials")
+			return
+		}
+	}
+
+	if t.Task.Debug {
+		args = append(args, "-vvvv")
+	}
+
+	if t.Task.DryRun {
+		args = append(args, "--check")
+	}
+
+	if t.Task.Diff {
+		args = append(args, "--diff")
+	}
+
+	if t.Template.VaultKeyID != nil {
+		args = append(args, "--vault-password-file", t.Template.VaultKey.GetPath())
+	}
+
+	extraVars, err := t.getEnvironmentExtraVars(false)
+	if err != nil {
+		t.Logger.Log(err.Error())
+		t.Logger.Log("Could not remove command environment, if existant it will be passed to --extra-vars. This is not fatal but be aware of side effects")
+	} else if extraVars != "" {
+		args = append(args, "--extra-vars", extraVars)
+	}
+
+	// secret values are read from the file, so they are not visible in the list of processes
+	if len(t.Task.SecretSurveyValues) > 0 {
+		args = append(args, "--extra-vars=@"+t.getSecretVarsPath())
+	}
+
+	var templateExtraArgs []string
+	if t.Template.Arguments != nil {
+		err = json.Unmarshal([]byte(*t.Template.Arguments), &templateExtraArgs)
+		if err != nil {
+			t.Logger.Log("Invalid format of the template extra arguments, must be valid JSON")
+			return
+		}
+	}
+
+	var taskExtraArgs []string
+	if t.Template.AllowOverrideArgsInTask && t.Task.Arguments != nil {
+		err = json.Unmarshal([]byte(*t.Task.Arguments), &taskExtraArgs)
+		if err != nil {
+			t.Logger.Log("Invalid format of the TaskRunner extra arguments, must be valid JSON")
+			return
+		}
+	}
+
+	if t.Task.Limit != "" {
+		t.Logger.Log("--limit=" + t.Task.Limit)
+		taskExtraArgs = append(taskExtraArgs, "--limit="+t.Task.Limit)
+	}
+
+	args = append(args, templateExtraArgs...)
+	args = append(args, taskExtraArgs...)
+	args = append(args, playbookName)
+
+	return
+}
@@ -12,7 +12,13 @@ import (
 )
 
 func (t *TaskRunner) Log(msg string) {
-	now := time.Now()
+	t.LogWithTime(time.Now(), msg)
+}
+
+// LogWithTime writes a line of task output which was produced at the specified time.
+// Secrets of the task are replaced with **** before the line is sent to users and stored.
+func (t *TaskRunner) LogWithTime(now time.Time, msg string) {
+	msg = t.secrets.mask(msg)
 
 	for _, user := range t.users {
 		b, err := json.Marshal(&map[string]interface{}{
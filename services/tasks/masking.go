@@ -0,0 +1,85 @@
+package tasks
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ansible-semaphore/semaphore/db"
+)
+
+// secretMask replaces secrets in task output.
+const secretMask = "****"
+
+// minSecretLength prevents masking of short lines of access keys and passwords
+// which occur in ordinary output, for example "yes" or "ok".
+const minSecretLength = 4
+
+// secretMasker replaces secrets which were installed for the task
+// in its output before the output is sent to users and stored.
+type secretMasker struct {
+	secrets  []string
+	patterns []*regexp.Regexp
+}
+
+func (m *secretMasker) addSecret(secret string) {
+	m.addLines(secret, minSecretLength)
+}
+
+// addSurveySecret adds a value of a secret survey variable. It is masked regardless of length,
+// because the user marked it as a secret explicitly.
+func (m *secretMasker) addSurveySecret(secret string) {
+	m.addLines(secret, 1)
+}
+
+func (m *secretMasker) addLines(secret string, minLength int) {
+	// multiline secrets, like private keys, are printed line by line
+	for _, line := range strings.Split(secret, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) < minLength {
+			continue
+		}
+		m.secrets = append(m.secrets, line)
+	}
+
+	// a secret which contains another secret must be replaced first
+	sort.SliceStable(m.secrets, func(i, j int) bool {
+		return len(m.secrets[i]) > len(m.secrets[j])
+	})
+}
+
+func (m *secretMasker) addAccessKey(key db.AccessKey) error {
+	err := key.DeserializeSecret()
+	if err != nil {
+		return err
+	}
+
+	m.addSecret(key.LoginPassword.Password)
+	m.addSecret(key.SshKey.Passphrase)
+	m.addSecret(key.SshKey.PrivateKey)
+	m.addSecret(key.PAT)
+
+	return nil
+}
+
+func (m *secretMasker) addPattern(pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+
+	m.patterns = append(m.patterns, re)
+	return nil
+}
+
+func (m *secretMasker) mask(msg string) string {
+	for _, secret := range m.secrets {
+		msg = strings.ReplaceAll(msg, secret, secretMask)
+	}
+
+	for _, re := range m.patterns {
+		msg = re.ReplaceAllString(msg, secretMask)
+	}
+
+	return msg
+}
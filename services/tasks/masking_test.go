@@ -0,0 +1,99 @@
+package tasks
+
+import (
+	"github.com/ansible-semaphore/semaphore/db"
+	"github.com/ansible-semaphore/semaphore/db/bolt"
+	"strings"
+	"testing"
+)
+
+func TestSecretMasker(t *testing.T) {
+	masker := secretMasker{}
+
+	err := masker.addAccessKey(db.AccessKey{
+		Type: db.AccessKeyLoginPassword,
+		LoginPassword: db.LoginPassword{
+			Login:    "root",
+			Password: "p4ssw0rd",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	masker.addSecret("p4ss")
+	masker.addSecret("ok")
+
+	err = masker.addPattern(`ghp_[A-Za-z0-9]+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := masker.mask("ok: login root, password p4ssw0rd, token ghp_abc123")
+	if res != "ok: login root, password ****, token ****" {
+		t.Fatal("unexpected result", res)
+	}
+}
+
+func TestSecretMaskerSurveySecret(t *testing.T) {
+	masker := secretMasker{}
+
+	masker.addSecret("abc")
+	masker.addSurveySecret("xyz")
+	masker.addSurveySecret("")
+
+	res := masker.mask("abc xyz")
+	if res != "abc ****" {
+		t.Fatal("unexpected result", res)
+	}
+}
+
+func TestSetDiffsMasksSecrets(t *testing.T) {
+	store := bolt.CreateTestStore()
+
+	proj, err := store.CreateProject(db.Project{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tpl, err := store.CreateTemplate(db.Template{Name: "Deploy", Playbook: "deploy.yml", ProjectID: proj.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task, err := store.CreateTask(db.Task{TemplateID: tpl.ID, ProjectID: proj.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := CreateTaskPool(&store)
+	tsk := TaskRunner{task: task, pool: &pool}
+	tsk.secrets.addSecret("s3cr3t-value")
+
+	tsk.SetDiffs([]db.TaskDiff{{
+		Host:         "web1",
+		TaskName:     "Write config",
+		BeforeHeader: "/etc/app.conf",
+		Before:       "password=old\n",
+		AfterHeader:  "/etc/app.conf",
+		After:        "password=s3cr3t-value\n",
+		Prepared:     "+password=s3cr3t-value",
+	}})
+
+	diffs, err := store.GetTaskDiffs(proj.ID, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(diffs) != 1 {
+		t.Fatal("diff must be stored")
+	}
+
+	if strings.Contains(diffs[0].After+diffs[0].Prepared, "s3cr3t-value") {
+		t.Fatal("secret must not be stored in the diff")
+	}
+
+	if diffs[0].After != "password=****\n" {
+		t.Fatal("unexpected content of the diff: " + diffs[0].After)
+	}
+}
@@ -0,0 +1,388 @@
+package tasks
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/ansible-semaphore/semaphore/db"
+)
+
+// pipelineStageState is the state of a pipeline stage in a run.
+type pipelineStageState int
+
+const (
+	// pipelineStageWaiting stages wait for the stages they need or for their task.
+	pipelineStageWaiting pipelineStageState = iota
+	// pipelineStageReady stages must be started.
+	pipelineStageReady
+	// pipelineStageSkipped stages will not run because their condition is not met.
+	pipelineStageSkipped
+	pipelineStageSucceeded
+	pipelineStageFailed
+)
+
+// pipelineRunState computes states of stages of a run from tasks started by the run.
+type pipelineRunState struct {
+	pipeline db.Pipeline
+	// tasks contains the last task of every started stage.
+	tasks  map[string]db.Task
+	states map[string]pipelineStageState
+}
+
+func newPipelineRunState(pipeline db.Pipeline, tasks []db.Task) *pipelineRunState {
+	s := &pipelineRunState{
+		pipeline: pipeline,
+		tasks:    make(map[string]db.Task),
+		states:   make(map[string]pipelineStageState),
+	}
+
+	// retries of a stage task are created later than the failed attempt
+	for _, task := range tasks {
+		s.tasks[task.PipelineStage] = task
+	}
+
+	return s
+}
+
+func (s *pipelineRunState) getState(name string) pipelineStageState {
+	if state, ok := s.states[name]; ok {
+		return state
+	}
+
+	state := s.computeState(name)
+	s.states[name] = state
+	return state
+}
+
+func (s *pipelineRunState) computeState(name string) pipelineStageState {
+	if task, ok := s.tasks[name]; ok {
+		switch {
+		case !task.Status.IsFinished():
+			return pipelineStageWaiting
+		case task.Status == db.TaskSuccessStatus:
+			return pipelineStageSucceeded
+		default:
+			return pipelineStageFailed
+		}
+	}
+
+	stage := s.pipeline.GetStage(name)
+
+	succeeded := true
+	failed := false
+
+	for _, need := range stage.Needs {
+		switch s.getState(need) {
+		case pipelineStageWaiting, pipelineStageReady:
+			return pipelineStageWaiting
+		case pipelineStageSucceeded:
+		case pipelineStageFailed:
+			succeeded = false
+			failed = true
+		default:
+			succeeded = false
+		}
+	}
+
+	switch stage.Condition {
+	case db.PipelineStageOnFailure:
+		if !failed {
+			return pipelineStageSkipped
+		}
+	case db.PipelineStageOnSuccess:
+		if !succeeded {
+			return pipelineStageSkipped
+		}
+	}
+
+	return pipelineStageReady
+}
+
+// getBuildTask returns the task which passes its version to the stage.
+// It is the first task of the needed stages which has a version.
+func (s *pipelineRunState) getBuildTask(stage db.PipelineStage) *int {
+	var res *int
+
+	for _, need := range stage.Needs {
+		task, ok := s.tasks[need]
+		if !ok {
+			continue
+		}
+
+		if task.Version != nil {
+			return &task.ID
+		}
+
+		if res == nil {
+			id := task.ID
+			res = &id
+		}
+	}
+
+	return res
+}
+
+// getEnvironment returns extra variables of the stage. Variables of the run are extended
+// with extra variables and survey answers of tasks of the needed stages, so variables
+// pass down the pipeline. Values of later needed stages override values of earlier ones.
+func (s *pipelineRunState) getEnvironment(stage db.PipelineStage, runEnvironment string) (string, error) {
+	vars := make(map[string]interface{})
+
+	if runEnvironment != "" {
+		if err := json.Unmarshal([]byte(runEnvironment), &vars); err != nil {
+			return "", err
+		}
+	}
+
+	for _, need := range stage.Needs {
+		task, ok := s.tasks[need]
+		if !ok {
+			continue
+		}
+
+		if task.Environment != "" {
+			if err := json.Unmarshal([]byte(task.Environment), &vars); err != nil {
+				return "", err
+			}
+		}
+
+		// secret answers are not stored, so they do not pass to other stages
+		for name, value := range task.SurveyValues {
+			vars[name] = value
+		}
+	}
+
+	if len(vars) == 0 {
+		return runEnvironment, nil
+	}
+
+	env, err := json.Marshal(vars)
+	if err != nil {
+		return "", err
+	}
+
+	return string(env), nil
+}
+
+// StartPipeline creates a run of the pipeline and starts its first stages.
+func (p *TaskPool) StartPipeline(pipeline db.Pipeline, environment string, userID *int) (run db.PipelineRun, err error) {
+	if environment != "" && !json.Valid([]byte(environment)) {
+		err = &db.ValidationError{Message: "pipeline environment must be valid JSON"}
+		return
+	}
+
+	for _, stage := range pipeline.Stages {
+		_, err = p.store.GetTemplate(pipeline.ProjectID, stage.TemplateID)
+		if err == db.ErrNotFound {
+			err = &db.ValidationError{Message: "template of pipeline stage " + stage.Name + " not found"}
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	run, err = p.store.CreatePipelineRun(db.PipelineRun{
+		ProjectID:   pipeline.ProjectID,
+		PipelineID:  pipeline.ID,
+		UserID:      userID,
+		Status:      db.TaskRunningStatus,
+		Environment: environment,
+	})
+
+	if err != nil {
+		return
+	}
+
+	p.createPipelineEvent(run, "Pipeline "+pipeline.Name+" run "+strconv.Itoa(run.ID)+" started")
+
+	p.advancePipelineRun(run.ProjectID, run.ID)
+
+	return p.store.GetPipelineRun(run.ProjectID, run.ID)
+}
+
+// StopPipelineRun stops active tasks of the run. Stages which are not started yet will not run.
+func (p *TaskPool) StopPipelineRun(run db.PipelineRun) error {
+	p.pipelineLock.Lock()
+
+	run, err := p.store.GetPipelineRun(run.ProjectID, run.ID)
+	if err != nil || run.Status.IsFinished() {
+		p.pipelineLock.Unlock()
+		return err
+	}
+
+	run.Status = db.TaskStoppingStatus
+	err = p.store.UpdatePipelineRun(run)
+
+	p.pipelineLock.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	tasks, err := p.store.GetPipelineRunTasks(run.ProjectID, run.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		if task.Status.IsFinished() || task.Status == db.TaskStoppingStatus {
+			continue
+		}
+
+		if err = p.StopTask(task); err != nil {
+			return err
+		}
+	}
+
+	p.advancePipelineRun(run.ProjectID, run.ID)
+
+	return nil
+}
+
+// onTaskFinished continues the pipeline run which started the task.
+// It runs asynchronously because starting of stages sends new tasks to the pool.
+func (p *TaskPool) onTaskFinished(task db.Task) {
+	if task.PipelineRunID == nil {
+		return
+	}
+
+	go p.advancePipelineRun(task.ProjectID, *task.PipelineRunID)
+}
+
+// advancePipelineRun starts stages of the run which are ready
+// and sets the status of the run when all its stages are finished.
+func (p *TaskPool) advancePipelineRun(projectID int, runID int) {
+	p.pipelineLock.Lock()
+	defer p.pipelineLock.Unlock()
+
+	run, err := p.store.GetPipelineRun(projectID, runID)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	if run.Status.IsFinished() {
+		return
+	}
+
+	pipeline, err := p.store.GetPipeline(projectID, run.PipelineID)
+	if err != nil {
+		log.Error(err)
+		p.finishPipelineRun(run, db.TaskFailStatus)
+		return
+	}
+
+	tasks, err := p.store.GetPipelineRunTasks(projectID, runID)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	state := newPipelineRunState(pipeline, tasks)
+
+	if run.Status == db.TaskStoppingStatus {
+		for _, task := range state.tasks {
+			if !task.Status.IsFinished() {
+				return
+			}
+		}
+
+		p.finishPipelineRun(run, db.TaskStoppedStatus)
+		return
+	}
+
+	finished := true
+	status := db.TaskSuccessStatus
+
+	for _, stage := range pipeline.Stages {
+		switch state.getState(stage.Name) {
+		case pipelineStageReady:
+			finished = false
+
+			err = p.startPipelineStage(run, stage, state)
+			if err != nil {
+				log.Error(err)
+				p.createPipelineEvent(run, "Pipeline "+pipeline.Name+" run "+strconv.Itoa(run.ID)+
+					" failed to start stage "+stage.Name+": "+err.Error())
+				p.finishPipelineRun(run, db.TaskFailStatus)
+				p.stopPipelineTasks(state)
+				return
+			}
+		case pipelineStageWaiting:
+			finished = false
+		case pipelineStageFailed:
+			status = db.TaskFailStatus
+		}
+	}
+
+	if finished {
+		p.finishPipelineRun(run, status)
+	}
+}
+
+func (p *TaskPool) startPipelineStage(run db.PipelineRun, stage db.PipelineStage, state *pipelineRunState) error {
+	environment, err := state.getEnvironment(stage, run.Environment)
+	if err != nil {
+		return err
+	}
+
+	task, err := p.AddTask(db.Task{
+		TemplateID:    stage.TemplateID,
+		ProjectID:     run.ProjectID,
+		Environment:   environment,
+		BuildTaskID:   state.getBuildTask(stage),
+		PipelineRunID: &run.ID,
+		PipelineStage: stage.Name,
+	}, run.UserID, run.ProjectID)
+
+	if err != nil {
+		return err
+	}
+
+	state.tasks[stage.Name] = task
+	return nil
+}
+
+// stopPipelineTasks stops active tasks of the run after the run failed.
+func (p *TaskPool) stopPipelineTasks(state *pipelineRunState) {
+	for _, task := range state.tasks {
+		if task.Status.IsFinished() || task.Status == db.TaskStoppingStatus {
+			continue
+		}
+
+		if err := p.StopTask(task); err != nil {
+			log.Error(err)
+		}
+	}
+}
+
+func (p *TaskPool) finishPipelineRun(run db.PipelineRun, status db.TaskStatus) {
+	now := time.Now()
+	run.Status = status
+	run.End = &now
+
+	if err := p.store.UpdatePipelineRun(run); err != nil {
+		log.Error(err)
+		return
+	}
+
+	p.createPipelineEvent(run, "Pipeline run "+strconv.Itoa(run.ID)+" finished - "+string(status))
+}
+
+func (p *TaskPool) createPipelineEvent(run db.PipelineRun, desc string) {
+	objType := db.EventPipeline
+
+	_, err := p.store.CreateEvent(db.Event{
+		UserID:      run.UserID,
+		ProjectID:   &run.ProjectID,
+		ObjectType:  &objType,
+		ObjectID:    &run.PipelineID,
+		Description: &desc,
+	})
+
+	if err != nil {
+		log.Error(err)
+	}
+}
@@ -0,0 +1,227 @@
+package tasks
+
+import (
+	"encoding/json"
+	"github.com/ansible-semaphore/semaphore/db"
+	"github.com/ansible-semaphore/semaphore/db/bolt"
+	"github.com/ansible-semaphore/semaphore/util"
+	"testing"
+)
+
+func TestPipelineRunState(t *testing.T) {
+	version := "1.0.0"
+
+	pipeline := db.Pipeline{
+		Stages: []db.PipelineStage{
+			{Name: "build", TemplateID: 1},
+			{Name: "test", TemplateID: 2},
+			{Name: "deploy", TemplateID: 3, Needs: []string{"build", "test"}},
+			{Name: "notify", TemplateID: 4, Needs: []string{"deploy"}, Condition: db.PipelineStageOnFailure},
+			{Name: "cleanup", TemplateID: 5, Needs: []string{"deploy"}, Condition: db.PipelineStageAlways},
+		},
+	}
+
+	state := newPipelineRunState(pipeline, []db.Task{
+		{ID: 1, PipelineStage: "build", Status: db.TaskSuccessStatus, Version: &version},
+		{ID: 2, PipelineStage: "test", Status: db.TaskRunningStatus},
+	})
+
+	if state.getState("deploy") != pipelineStageWaiting {
+		t.Fatal("stage must wait for all needed stages")
+	}
+
+	state = newPipelineRunState(pipeline, []db.Task{
+		{ID: 1, PipelineStage: "build", Status: db.TaskSuccessStatus, Version: &version},
+		{ID: 2, PipelineStage: "test", Status: db.TaskFailStatus},
+		{ID: 3, PipelineStage: "test", Status: db.TaskSuccessStatus},
+	})
+
+	if state.getState("deploy") != pipelineStageReady {
+		t.Fatal("stage must be ready when the last attempts of needed stages succeeded")
+	}
+
+	if id := state.getBuildTask(*pipeline.GetStage("deploy")); id == nil || *id != 1 {
+		t.Fatal("version must be taken from the needed stage which has it")
+	}
+
+	state = newPipelineRunState(pipeline, []db.Task{
+		{ID: 1, PipelineStage: "build", Status: db.TaskSuccessStatus},
+		{ID: 2, PipelineStage: "test", Status: db.TaskSuccessStatus},
+		{ID: 3, PipelineStage: "deploy", Status: db.TaskSuccessStatus},
+	})
+
+	if state.getState("notify") != pipelineStageSkipped || state.getState("cleanup") != pipelineStageReady {
+		t.Fatal("conditions of stages must be applied")
+	}
+
+	state = newPipelineRunState(pipeline, []db.Task{
+		{ID: 1, PipelineStage: "build", Status: db.TaskFailStatus},
+		{ID: 2, PipelineStage: "test", Status: db.TaskSuccessStatus},
+	})
+
+	if state.getState("deploy") != pipelineStageSkipped ||
+		state.getState("notify") != pipelineStageSkipped ||
+		state.getState("cleanup") != pipelineStageReady {
+		t.Fatal("skipped stages must not fail stages which need them")
+	}
+}
+
+func TestPipelineValidate(t *testing.T) {
+	pipeline := db.Pipeline{
+		Name: "Release",
+		Stages: []db.PipelineStage{
+			{Name: "a", TemplateID: 1, Needs: []string{"b"}},
+			{Name: "b", TemplateID: 2, Needs: []string{"a"}},
+		},
+	}
+
+	if pipeline.Validate() == nil {
+		t.Fatal("cyclic pipelines must be rejected")
+	}
+
+	pipeline.Stages[0].Needs = nil
+
+	if err := pipeline.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStartPipelineStageAfterFanIn(t *testing.T) {
+	oldConfig := util.Config
+	util.Config = &util.ConfigType{}
+	defer func() { util.Config = oldConfig }()
+
+	store := bolt.CreateTestStore()
+
+	proj, err := store.CreateProject(db.Project{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := store.CreateAccessKey(db.AccessKey{ProjectID: &proj.ID, Type: db.AccessKeyNone})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := store.CreateRepository(db.Repository{
+		ProjectID: proj.ID,
+		SSHKeyID:  key.ID,
+		Name:      "Test",
+		GitURL:    "git@example.com:test/test",
+		GitBranch: "master",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inv, err := store.CreateInventory(db.Inventory{ProjectID: proj.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := store.CreateEnvironment(db.Environment{ProjectID: proj.ID, Name: "test", JSON: "{}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tpl, err := store.CreateTemplate(db.Template{
+		Name:          "Test",
+		Playbook:      "test.yml",
+		ProjectID:     proj.ID,
+		RepositoryID:  repo.ID,
+		InventoryID:   inv.ID,
+		EnvironmentID: &env.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pipeline, err := store.CreatePipeline(db.Pipeline{
+		ProjectID: proj.ID,
+		Name:      "Release",
+		Stages: []db.PipelineStage{
+			{Name: "build", TemplateID: tpl.ID},
+			{Name: "test", TemplateID: tpl.ID},
+			{Name: "deploy", TemplateID: tpl.ID, Needs: []string{"build", "test"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run, err := store.CreatePipelineRun(db.PipelineRun{
+		ProjectID:   proj.ID,
+		PipelineID:  pipeline.ID,
+		Status:      db.TaskRunningStatus,
+		Environment: `{"region": "eu", "stage": "run"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upstream := []db.Task{
+		{
+			PipelineStage: "build",
+			Environment:   `{"region": "eu", "stage": "run", "artifact": "app.tar"}`,
+			SurveyValues:  map[string]interface{}{"owner": "ops"},
+		},
+		{
+			PipelineStage: "test",
+			Environment:   `{"region": "eu", "stage": "test"}`,
+		},
+	}
+
+	for _, task := range upstream {
+		task.TemplateID = tpl.ID
+		task.ProjectID = proj.ID
+		task.PipelineRunID = &run.ID
+		task.Status = db.TaskSuccessStatus
+		_, err = store.CreateTask(task)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tasks, err := store.GetPipelineRunTasks(proj.ID, run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := newPipelineRunState(pipeline, tasks)
+
+	if state.getState("deploy") != pipelineStageReady {
+		t.Fatal("stage must be ready after the needed stages succeeded")
+	}
+
+	pool := CreateTaskPool(&store)
+	go func() {
+		<-pool.register
+	}()
+
+	err = pool.startPipelineStage(run, *pipeline.GetStage("deploy"), state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var vars map[string]interface{}
+	if err = json.Unmarshal([]byte(state.tasks["deploy"].Environment), &vars); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"region":   "eu",
+		"stage":    "test",
+		"artifact": "app.tar",
+		"owner":    "ops",
+	}
+
+	if len(vars) != len(expected) {
+		t.Fatal("unexpected variables of the stage: ", vars)
+	}
+
+	for name, value := range expected {
+		if vars[name] != value {
+			t.Fatal("variable "+name+" must pass from the needed stages: ", vars)
+		}
+	}
+}
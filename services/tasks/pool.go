@@ -5,12 +5,21 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/ansible-semaphore/semaphore/util"
 )
 
+const (
+	// logBatchSize is the maximum number of log records written to database at once.
+	logBatchSize = 1000
+
+	// logFlushInterval is the maximum time a log record waits before it is written to database.
+	logFlushInterval = 500 * time.Millisecond
+)
+
 type logRecord struct {
 	task   *TaskRunner
 	output string
@@ -23,6 +32,10 @@ type resourceLock struct {
 }
 
 type TaskPool struct {
+	// queueLock protects queue, activeProj and runningTasks. They are changed only by
+	// the goroutine of the pool, but they are read by handlers of API requests.
+	queueLock sync.RWMutex
+
 	// queue contains list of tasks in status TaskWaitingStatus.
 	queue []*TaskRunner
 
@@ -40,9 +53,27 @@ type TaskPool struct {
 	store db.Store
 
 	resourceLocker chan *resourceLock
+
+	// remoteJobs channel used to pass tasks to remote runners.
+	remoteJobs chan *RemoteJob
+
+	// pipelineLock serializes changes of pipeline runs.
+	pipelineLock sync.Mutex
+
+	// awaitingApproval contains tasks in status TaskAwaitingApprovalStatus.
+	awaitingApproval map[int]*TaskRunner
+	awaitingLock     sync.Mutex
+
+	// stop channel is closed when the pool is destroyed.
+	stop chan struct{}
+
+	// logsWritten channel is closed when all log records are put to database after the pool is destroyed.
+	logsWritten chan struct{}
 }
 
 func (p *TaskPool) GetTask(id int) (task *TaskRunner) {
+	p.queueLock.RLock()
+	defer p.queueLock.RUnlock()
 
 	for _, t := range p.queue {
 		if t.task.ID == id {
@@ -63,94 +94,237 @@ func (p *TaskPool) GetTask(id int) (task *TaskRunner) {
 	return
 }
 
-//nolint: gocyclo
 func (p *TaskPool) Run() {
-	ticker := time.NewTicker(5 * time.Second)
+	go p.writeLogs()
 
-	defer func() {
-		close(p.resourceLocker)
-		ticker.Stop()
-	}()
+	p.restoreTasks()
 
-	// Lock or unlock resources when running a TaskRunner
-	go func(locker <-chan *resourceLock) {
-		for l := range locker {
-			t := l.holder
+	p.queueLock.Lock()
+	p.dispatch()
+	p.queueLock.Unlock()
 
-			if l.lock {
-				if p.blocks(t) {
-					panic("Trying to lock an already locked resource!")
-				}
-
-				projTasks, ok := p.activeProj[t.task.ProjectID]
-				if !ok {
-					projTasks = make(map[int]*TaskRunner)
-					p.activeProj[t.task.ProjectID] = projTasks
-				}
-				projTasks[t.task.ID] = t
-				p.runningTasks[t.task.ID] = t
+	for {
+		select {
+		case task := <-p.register: // new task created by API or schedule
+			// tasks created while the pool started could be restored from database already
+			if p.GetTask(task.task.ID) != nil {
 				continue
 			}
+			p.queueLock.Lock()
+			p.enqueue(task)
+			p.queueLock.Unlock()
+			log.Debug(task)
+			msg := "Task " + strconv.Itoa(task.task.ID) + " added to queue"
+			task.Log(msg)
+			log.Info(msg)
+			task.updateStatus()
+			p.queueLock.Lock()
+			p.dispatch()
+			p.queueLock.Unlock()
+		case l := <-p.resourceLocker: // running task finished and released its slot
+			p.queueLock.Lock()
+			p.setResourceLock(l)
+			p.dispatch()
+			p.queueLock.Unlock()
+		}
+	}
+}
 
-			if p.activeProj[t.task.ProjectID] != nil && p.activeProj[t.task.ProjectID][t.task.ID] != nil {
-				delete(p.activeProj[t.task.ProjectID], t.task.ID)
-				if len(p.activeProj[t.task.ProjectID]) == 0 {
-					delete(p.activeProj, t.task.ProjectID)
-				}
-			}
+// writeLogs puts log records to database in batches. It runs separately from dispatching
+// of tasks, so chatty tasks do not delay other tasks.
+func (p *TaskPool) writeLogs() {
+	ticker := time.NewTicker(logFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]db.TaskOutput, 0, logBatchSize)
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
 
-			delete(p.runningTasks, t.task.ID)
+		if err := p.store.CreateTaskOutputs(batch); err != nil {
+			log.Error(err)
 		}
-	}(p.resourceLocker)
+
+		batch = batch[:0]
+	}
+
+	add := func(record logRecord) {
+		batch = append(batch, db.TaskOutput{
+			TaskID: record.task.task.ID,
+			Output: record.output,
+			Time:   record.time,
+		})
+
+		if len(batch) >= logBatchSize {
+			flush()
+		}
+	}
 
 	for {
 		select {
 		case record := <-p.logger: // new log message which should be put to database
-			_, err := record.task.pool.store.CreateTaskOutput(db.TaskOutput{
-				TaskID: record.task.task.ID,
-				Output: record.output,
-				Time:   record.time,
-			})
-
-			if err != nil {
-				log.Error(err)
+			add(record)
+		case <-ticker.C:
+			flush()
+		case <-p.stop: // pool destroyed, write records which are received already
+			defer close(p.logsWritten)
+			for {
+				select {
+				case record := <-p.logger:
+					add(record)
+				default:
+					flush()
+					return
+				}
 			}
-		case task := <-p.register: // new task created by API or schedule
-			p.queue = append(p.queue, task)
-			log.Debug(task)
-			msg := "Task " + strconv.Itoa(task.task.ID) + " added to queue"
-			task.Log(msg)
-			log.Info(msg)
-			task.updateStatus()
+		}
+	}
+}
 
-		case <-ticker.C: // timer 5 seconds
-			if len(p.queue) == 0 {
-				continue
-			}
+// enqueue puts the task to the queue after all tasks with the same or higher priority.
+func (p *TaskPool) enqueue(t *TaskRunner) {
+	priority := t.priority()
 
-			//get TaskRunner from top of queue
-			t := p.queue[0]
-			if t.task.Status == db.TaskFailStatus {
-				//delete failed TaskRunner from queue
-				p.queue = p.queue[1:]
-				log.Info("Task " + strconv.Itoa(t.task.ID) + " removed from queue")
-				continue
-			}
-			if p.blocks(t) {
-				//move blocked TaskRunner to end of queue
-				p.queue = append(p.queue[1:], t)
-				continue
-			}
-			log.Info("Set resource locker with TaskRunner " + strconv.Itoa(t.task.ID))
-			p.resourceLocker <- &resourceLock{lock: true, holder: t}
-			if !t.prepared {
-				go t.prepareRun()
-				continue
-			}
-			go t.run()
-			p.queue = p.queue[1:]
+	i := len(p.queue)
+	for i > 0 && p.queue[i-1].priority() < priority {
+		i--
+	}
+
+	p.queue = append(p.queue, nil)
+	copy(p.queue[i+1:], p.queue[i:])
+	p.queue[i] = t
+}
+
+// dispatch starts all queued tasks which are not blocked by running tasks.
+// Blocked tasks keep their position in the queue. The caller must hold queueLock.
+func (p *TaskPool) dispatch() {
+	i := 0
+
+	for i < len(p.queue) {
+		if len(p.runningTasks) >= util.Config.MaxParallelTasks {
+			return
+		}
+
+		t := p.queue[i]
+
+		if t.task.Status == db.TaskFailStatus {
+			//delete failed TaskRunner from queue
+			p.queue = append(p.queue[:i], p.queue[i+1:]...)
 			log.Info("Task " + strconv.Itoa(t.task.ID) + " removed from queue")
+			continue
+		}
+
+		if p.blocks(t) {
+			i++
+			continue
+		}
+
+		log.Info("Set resource locker with TaskRunner " + strconv.Itoa(t.task.ID))
+		p.setResourceLock(&resourceLock{lock: true, holder: t})
+		go t.run()
+		p.queue = append(p.queue[:i], p.queue[i+1:]...)
+		log.Info("Task " + strconv.Itoa(t.task.ID) + " removed from queue")
+	}
+}
+
+// setResourceLock marks the task as running or removes it from running tasks.
+func (p *TaskPool) setResourceLock(l *resourceLock) {
+	t := l.holder
+
+	if l.lock {
+		if p.blocks(t) {
+			panic("Trying to lock an already locked resource!")
+		}
+
+		projTasks, ok := p.activeProj[t.task.ProjectID]
+		if !ok {
+			projTasks = make(map[int]*TaskRunner)
+			p.activeProj[t.task.ProjectID] = projTasks
+		}
+		projTasks[t.task.ID] = t
+		p.runningTasks[t.task.ID] = t
+		return
+	}
+
+	if p.activeProj[t.task.ProjectID] != nil && p.activeProj[t.task.ProjectID][t.task.ID] != nil {
+		delete(p.activeProj[t.task.ProjectID], t.task.ID)
+		if len(p.activeProj[t.task.ProjectID]) == 0 {
+			delete(p.activeProj, t.task.ProjectID)
+		}
+	}
+
+	delete(p.runningTasks, t.task.ID)
+}
+
+// restoreTasks reconciles tasks left in the database by the previous server run.
+// Waiting tasks are put back to the queue in their original order and tasks awaiting
+// approval wait for it again. Running and stopping tasks have no process anymore,
+// so they are marked as failed.
+func (p *TaskPool) restoreTasks() {
+	tasks, err := p.store.GetTasksByStatus([]db.TaskStatus{
+		db.TaskWaitingStatus,
+		db.TaskAwaitingApprovalStatus,
+		db.TaskRunningStatus,
+		db.TaskStoppingStatus,
+	})
+
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	for _, task := range tasks {
+		t := &TaskRunner{
+			task: task,
+			pool: p,
+		}
+
+		if task.Status != db.TaskWaitingStatus && task.Status != db.TaskAwaitingApprovalStatus {
+			msg := "Task " + strconv.Itoa(task.ID) + " was interrupted by server restart"
+			t.Log(msg)
+			log.Warn(msg)
+
+			now := time.Now()
+			t.task.Status = db.TaskFailStatus
+			t.task.End = &now
+			t.updateStatus()
+			p.onTaskFinished(t.task)
+			continue
+		}
+
+		err = t.populateDetails()
+		if err != nil {
+			t.Log("Error: " + err.Error())
+			t.fail()
+			p.onTaskFinished(t.task)
+			continue
+		}
+
+		if t.template.HasSecretSurveyVars() {
+			t.Log("Secret survey values are not stored and were lost on server restart")
+			t.fail()
+			p.onTaskFinished(t.task)
+			continue
+		}
+
+		t.createJob()
+
+		if task.Status == db.TaskAwaitingApprovalStatus {
+			p.awaitingLock.Lock()
+			p.awaitingApproval[task.ID] = t
+			p.awaitingLock.Unlock()
+			log.Info("Task " + strconv.Itoa(task.ID) + " awaits approval after server restart")
+			continue
 		}
+
+		p.queueLock.Lock()
+		p.enqueue(t)
+		p.queueLock.Unlock()
+		msg := "Task " + strconv.Itoa(task.ID) + " restored to queue after server restart"
+		t.Log(msg)
+		log.Info(msg)
 	}
 }
 
@@ -160,12 +334,16 @@ func (p *TaskPool) blocks(t *TaskRunner) bool {
 		return true
 	}
 
+	if p.sharesLockGroup(t) {
+		return true
+	}
+
 	if p.activeProj[t.task.ProjectID] == nil || len(p.activeProj[t.task.ProjectID]) == 0 {
 		return false
 	}
 
 	for _, r := range p.activeProj[t.task.ProjectID] {
-		if r.template.ID == t.task.TemplateID {
+		if r.template.ID == t.task.TemplateID && t.clashesWith(r) {
 			return true
 		}
 	}
@@ -180,6 +358,45 @@ func (p *TaskPool) blocks(t *TaskRunner) bool {
 	return proj.MaxParallelTasks > 0 && len(p.activeProj[t.task.ProjectID]) >= proj.MaxParallelTasks
 }
 
+// clashesWith returns true if the task can not run together with the active task of the same template.
+func (t *TaskRunner) clashesWith(r *TaskRunner) bool {
+	switch t.template.ConcurrencyMode {
+	case db.TemplateConcurrencyParallel:
+		return false
+	case db.TemplateConcurrencyPerTarget:
+		// tasks of the same template use the same inventory
+		return t.task.Limit == r.task.Limit
+	default:
+		return true
+	}
+}
+
+// sharesLockGroup returns true if a running task of another template of any project
+// holds a lock group of the task.
+func (p *TaskPool) sharesLockGroup(t *TaskRunner) bool {
+	groups := t.template.GetLockGroups()
+	if len(groups) == 0 {
+		return false
+	}
+
+	for _, r := range p.runningTasks {
+		// tasks of the same template are limited by its concurrency mode
+		if r.template.ID == t.template.ID {
+			continue
+		}
+
+		for _, group := range r.template.GetLockGroups() {
+			for _, g := range groups {
+				if g == group {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
 func CreateTaskPool(store db.Store) TaskPool {
 	return TaskPool{
 		queue:          make([]*TaskRunner, 0), // queue of waiting tasks
@@ -189,33 +406,44 @@ func CreateTaskPool(store db.Store) TaskPool {
 		logger:         make(chan logRecord, 10000), // store log records to database
 		store:          store,
 		resourceLocker: make(chan *resourceLock),
+		remoteJobs:     make(chan *RemoteJob),
+
+		awaitingApproval: make(map[int]*TaskRunner),
+
+		stop:        make(chan struct{}),
+		logsWritten: make(chan struct{}),
 	}
 }
 
+// Destroy stops the pool and waits until buffered log records are put to database.
+// The pool must be running.
+func (p *TaskPool) Destroy() {
+	close(p.stop)
+	<-p.logsWritten
+}
+
 func (p *TaskPool) StopTask(targetTask db.Task) error {
 	tsk := p.GetTask(targetTask.ID)
 	if tsk == nil { // task not active, but exists in database
-		tsk = &TaskRunner{
-			task: targetTask,
-			pool: p,
-		}
-		err := tsk.populateDetails()
-		if err != nil {
-			return err
+		tsk = p.takeAwaitingTask(targetTask.ID)
+		if tsk == nil {
+			tsk = &TaskRunner{
+				task: targetTask,
+				pool: p,
+			}
+			err := tsk.populateDetails()
+			if err != nil {
+				return err
+			}
 		}
 		tsk.setStatus(db.TaskStoppedStatus)
 		tsk.createTaskEvent()
+		p.onTaskFinished(tsk.task)
 	} else {
 		status := tsk.task.Status
 		tsk.setStatus(db.TaskStoppingStatus)
 		if status == db.TaskRunningStatus {
-			if tsk.process == nil {
-				panic("running process can not be nil")
-			}
-			err := tsk.process.Kill()
-			if err != nil {
-				return err
-			}
+			tsk.job.Kill()
 		}
 	}
 
@@ -281,6 +509,7 @@ func (p *TaskPool) AddTask(taskObj db.Task, userID *int, projectID int) (newTask
 	taskObj.Status = db.TaskWaitingStatus
 	taskObj.UserID = userID
 	taskObj.ProjectID = projectID
+	taskObj.Attempt = taskObj.GetAttempt()
 
 	tpl, err := p.store.GetTemplate(projectID, taskObj.TemplateID)
 	if err != nil {
@@ -292,20 +521,43 @@ func (p *TaskPool) AddTask(taskObj db.Task, userID *int, projectID int) (newTask
 		return
 	}
 
-	if tpl.Type == db.TemplateBuild { // get next version for TaskRunner if it is a Build
+	if taskObj.PipelineRunID != nil {
+		var run db.PipelineRun
+		run, err = p.store.GetPipelineRun(projectID, *taskObj.PipelineRunID)
+		if err != nil {
+			return
+		}
+		if run.Status != db.TaskRunningStatus {
+			err = &db.ValidationError{Message: "pipeline run " + strconv.Itoa(run.ID) + " is not running"}
+			return
+		}
+	}
+
+	if tpl.Type == db.TemplateBuild && taskObj.RetryOfTaskID == nil { // get next version for TaskRunner if it is a Build
 		var builds []db.TaskWithTpl
 		builds, err = p.store.GetTemplateTasks(tpl.ProjectID, tpl.ID, db.RetrieveQueryParams{Count: 1})
 		if err != nil {
 			return
 		}
-		if len(builds) == 0 || builds[0].Version == nil {
-			taskObj.Version = tpl.StartVersion
+		var lastVersion *string
+		if len(builds) > 0 {
+			lastVersion = builds[0].Version
+		}
+		if taskObj.ValidateOnly {
+			// validation builds nothing, so the next build continues from the last version
+			taskObj.Version = lastVersion
 		} else {
-			v := getNextBuildVersion(*tpl.StartVersion, *builds[0].Version)
-			taskObj.Version = &v
+			taskObj.Version = getNextVersion(tpl, lastVersion, time.Now())
 		}
 	}
 
+	// dry runs and validations change nothing, so they can be used to review changes
+	// before approving the real run
+	if tpl.Type == db.TemplateDeploy && tpl.RequireApproval && !taskObj.SkipApproval &&
+		!taskObj.DryRun && !taskObj.ValidateOnly {
+		taskObj.Status = db.TaskAwaitingApprovalStatus
+	}
+
 	newTask, err = p.store.CreateTask(taskObj)
 	if err != nil {
 		return
@@ -320,6 +572,14 @@ func (p *TaskPool) AddTask(taskObj db.Task, userID *int, projectID int) (newTask
 	if err != nil {
 		taskRunner.Log("Error: " + err.Error())
 		taskRunner.fail()
+		p.onTaskFinished(taskRunner.task)
+		return
+	}
+
+	taskRunner.createJob()
+
+	if newTask.Status == db.TaskAwaitingApprovalStatus {
+		p.holdForApproval(&taskRunner)
 		return
 	}
 
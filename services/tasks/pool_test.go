@@ -0,0 +1,181 @@
+package tasks
+
+import (
+	"github.com/ansible-semaphore/semaphore/db"
+	"github.com/ansible-semaphore/semaphore/db/bolt"
+	"github.com/ansible-semaphore/semaphore/util"
+	"testing"
+	"time"
+)
+
+func TestTaskPoolEnqueue(t *testing.T) {
+	pool := CreateTaskPool(nil)
+
+	high := 10
+	tasks := []*TaskRunner{
+		{task: db.Task{ID: 1}},
+		{task: db.Task{ID: 2}, template: db.Template{Priority: 5}},
+		{task: db.Task{ID: 3}},
+		{task: db.Task{ID: 4, Priority: &high}},
+		{task: db.Task{ID: 5}, template: db.Template{Priority: 5}},
+	}
+
+	for _, tsk := range tasks {
+		pool.enqueue(tsk)
+	}
+
+	expected := []int{4, 2, 5, 1, 3}
+
+	for i, tsk := range pool.queue {
+		if tsk.task.ID != expected[i] {
+			t.Fatal("unexpected queue order at", i, "task", tsk.task.ID)
+		}
+	}
+}
+
+func TestTaskPoolBlocksLockGroups(t *testing.T) {
+	util.Config = &util.ConfigType{MaxParallelTasks: 10}
+
+	pool := CreateTaskPool(nil)
+
+	running := &TaskRunner{
+		task:     db.Task{ID: 1, ProjectID: 1, TemplateID: 1},
+		template: db.Template{ID: 1, ProjectID: 1, InventoryID: 5, ConcurrencyGroups: []string{"prod-db"}},
+	}
+	pool.runningTasks[running.task.ID] = running
+
+	sameGroup := &TaskRunner{
+		task:     db.Task{ID: 2, ProjectID: 2, TemplateID: 2},
+		template: db.Template{ID: 2, ProjectID: 2, ConcurrencyGroups: []string{"web", "prod-db"}},
+	}
+	if !pool.sharesLockGroup(sameGroup) {
+		t.Fatal("tasks of the same group must not run at the same time")
+	}
+
+	otherGroup := &TaskRunner{
+		task:     db.Task{ID: 3, ProjectID: 2, TemplateID: 3},
+		template: db.Template{ID: 3, ProjectID: 2, InventoryID: 5, ConcurrencyGroups: []string{"web"}},
+	}
+	if pool.sharesLockGroup(otherGroup) {
+		t.Fatal("tasks of different groups must run in parallel")
+	}
+
+	running.template.LockInventory = true
+	otherGroup.template.LockInventory = true
+	if !pool.sharesLockGroup(otherGroup) {
+		t.Fatal("tasks with the same locked inventory must not run at the same time")
+	}
+
+	sameTemplate := &TaskRunner{
+		task:     db.Task{ID: 4, ProjectID: 1, TemplateID: 1},
+		template: running.template,
+	}
+	sameTemplate.template.ConcurrencyMode = db.TemplateConcurrencyParallel
+	if pool.sharesLockGroup(sameTemplate) {
+		t.Fatal("tasks of the same template must be limited by its concurrency mode only")
+	}
+}
+
+func TestTaskRunnerClashesWith(t *testing.T) {
+	running := &TaskRunner{
+		task:     db.Task{ID: 1, TemplateID: 1, Limit: "web"},
+		template: db.Template{ID: 1, InventoryID: 5},
+	}
+
+	task := &TaskRunner{
+		task:     db.Task{ID: 2, TemplateID: 1, Limit: "db"},
+		template: db.Template{ID: 1, InventoryID: 5},
+	}
+
+	if !task.clashesWith(running) {
+		t.Fatal("tasks of the same template must run one by one by default")
+	}
+
+	task.template.ConcurrencyMode = db.TemplateConcurrencyParallel
+	if task.clashesWith(running) {
+		t.Fatal("tasks of the parallel template must run at the same time")
+	}
+
+	task.template.ConcurrencyMode = db.TemplateConcurrencyPerTarget
+	if task.clashesWith(running) {
+		t.Fatal("tasks with different limits must run at the same time")
+	}
+
+	task.task.Limit = "web"
+	if !task.clashesWith(running) {
+		t.Fatal("tasks with the same inventory and limit must run one by one")
+	}
+}
+
+func TestTaskPoolDestroyFlushesLogs(t *testing.T) {
+	store := bolt.CreateTestStore()
+
+	proj, err := store.CreateProject(db.Project{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tpl, err := store.CreateTemplate(db.Template{Name: "Deploy", Playbook: "deploy.yml", ProjectID: proj.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task, err := store.CreateTask(db.Task{TemplateID: tpl.ID, ProjectID: proj.ID, Status: db.TaskRunningStatus})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := CreateTaskPool(&store)
+	go pool.writeLogs()
+
+	tsk := &TaskRunner{task: task, pool: &pool}
+	for i := 0; i < 3; i++ {
+		pool.logger <- logRecord{task: tsk, output: "line", time: time.Now()}
+	}
+
+	pool.Destroy()
+
+	output, err := store.GetTaskOutputs(proj.ID, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(output) != 3 {
+		t.Fatal("buffered log records must be written when the pool is destroyed", len(output))
+	}
+}
+
+func TestTaskPoolGetTaskWhileDispatching(t *testing.T) {
+	oldConfig := util.Config
+	util.Config = &util.ConfigType{MaxParallelTasks: 10}
+	defer func() { util.Config = oldConfig }()
+
+	store := bolt.CreateTestStore()
+	pool := CreateTaskPool(&store)
+	go pool.Run()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			pool.GetTask(i % 5)
+		}
+	}()
+
+	for i := 0; i < 1000; i++ {
+		tsk := &TaskRunner{task: db.Task{ID: i % 5, ProjectID: 1}}
+		pool.resourceLocker <- &resourceLock{lock: true, holder: tsk}
+		pool.resourceLocker <- &resourceLock{lock: false, holder: tsk}
+	}
+
+	<-done
+
+	running := &TaskRunner{task: db.Task{ID: 7, ProjectID: 1}}
+	pool.resourceLocker <- &resourceLock{lock: true, holder: running}
+	// the pool receives the next message after it handles the previous one
+	pool.resourceLocker <- &resourceLock{lock: false, holder: &TaskRunner{task: db.Task{ID: 8, ProjectID: 1}}}
+
+	if pool.GetTask(7) != running {
+		t.Fatal("running task must be found")
+	}
+}
@@ -0,0 +1,431 @@
+package tasks
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/ansible-semaphore/semaphore/db"
+	"github.com/ansible-semaphore/semaphore/lib"
+	"github.com/ansible-semaphore/semaphore/util"
+)
+
+// RemoteJobData contains everything a remote runner needs to run a task
+// without access to the database.
+type RemoteJobData struct {
+	Username        string               `json:"username"`
+	IncomingVersion *string              `json:"incoming_version"`
+	Task            db.Task              `json:"task"`
+	Template        db.Template          `json:"template"`
+	Inventory       db.Inventory         `json:"inventory"`
+	Repository      db.Repository        `json:"repository"`
+	Environment     db.Environment       `json:"environment"`
+	AccessKeys      map[int]db.AccessKey `json:"access_keys"`
+	BuildArtifacts  []db.TaskArtifact    `json:"build_artifacts"`
+	// SecretSurveyValues are passed separately because they are not serialized with the task.
+	SecretSurveyValues map[string]interface{} `json:"secret_survey_values"`
+}
+
+// RemoteLogRecord is a line of task output produced by a remote runner.
+type RemoteLogRecord struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// RemoteJobProgress is sent by a runner to report output and state of a job.
+type RemoteJobProgress struct {
+	ID            int               `json:"id"`
+	Status        db.TaskStatus     `json:"status"`
+	LogRecords    []RemoteLogRecord `json:"log_records"`
+	CommitHash    *string           `json:"commit_hash"`
+	CommitMessage string            `json:"commit_message"`
+	// UnreachableHosts is true if the job failed because some hosts were unreachable.
+	UnreachableHosts bool `json:"unreachable_hosts"`
+	// Hosts contains per-host results of the playbook. It is sent once when the playbook finishes.
+	Hosts []db.TaskHost `json:"hosts"`
+	// Diffs contains changes reported by the playbook. It is sent once when the playbook finishes.
+	Diffs []db.TaskDiff `json:"diffs"`
+	// Annotations contains problems found by validation of the playbook. They are sent once.
+	Annotations []db.TaskAnnotation `json:"annotations"`
+	// Version is the version of the build. It is sent once when the job finds it out.
+	Version *string `json:"version"`
+}
+
+// RemoteJobState is the state of a task as it is known by the server.
+type RemoteJobState struct {
+	ID     int           `json:"id"`
+	Status db.TaskStatus `json:"status"`
+}
+
+// RunnerState is returned to a runner polling for new jobs.
+type RunnerState struct {
+	NewJobs []RemoteJobData `json:"new_jobs"`
+}
+
+// RunnerProgress is sent by a runner periodically.
+type RunnerProgress struct {
+	Jobs []RemoteJobProgress `json:"jobs"`
+}
+
+// RunnerProgressResult is returned to a runner in reply to RunnerProgress.
+// Jobs which are stopping on the server must be killed by the runner.
+type RunnerProgressResult struct {
+	Jobs []RemoteJobState `json:"jobs"`
+}
+
+// CreateLocalJob makes a job which runs the task on the current host.
+func (d RemoteJobData) CreateLocalJob(logger JobLogger) *LocalJob {
+	job := &LocalJob{
+		Task:           d.Task,
+		Template:       d.Template,
+		Inventory:      d.Inventory,
+		Repository:     d.Repository,
+		Environment:    d.Environment,
+		BuildArtifacts: d.BuildArtifacts,
+		Logger:         logger,
+	}
+
+	job.Task.SecretSurveyValues = d.SecretSurveyValues
+
+	if job.Inventory.SSHKeyID != nil {
+		job.Inventory.SSHKey = d.AccessKeys[*job.Inventory.SSHKeyID]
+	}
+
+	if job.Inventory.BecomeKeyID != nil {
+		job.Inventory.BecomeKey = d.AccessKeys[*job.Inventory.BecomeKeyID]
+	}
+
+	if job.Template.VaultKeyID != nil {
+		job.Template.VaultKey = d.AccessKeys[*job.Template.VaultKeyID]
+	}
+
+	job.Repository.SSHKey = d.AccessKeys[job.Repository.SSHKeyID]
+
+	return job
+}
+
+// RemoteJob passes a task to a remote runner and waits until the runner reports
+// that the task is finished.
+type RemoteJob struct {
+	taskRunner *TaskRunner
+
+	runnerID int
+	mutex    sync.Mutex
+
+	username        string
+	incomingVersion *string
+
+	killed   chan struct{}
+	killOnce sync.Once
+	finished chan remoteJobResult
+	// progress receives a value every time the runner reports progress of the job.
+	progress chan struct{}
+	// returned receives a value if the runner did not receive the job.
+	returned chan struct{}
+}
+
+// remoteJobResult is the result of the job reported by the runner.
+type remoteJobResult struct {
+	status           db.TaskStatus
+	unreachableHosts bool
+}
+
+func createRemoteJob(t *TaskRunner) *RemoteJob {
+	return &RemoteJob{
+		taskRunner: t,
+		killed:     make(chan struct{}),
+		finished:   make(chan remoteJobResult, 1),
+		progress:   make(chan struct{}, 1),
+		returned:   make(chan struct{}, 1),
+	}
+}
+
+func (j *RemoteJob) Run(username string, incomingVersion *string) error {
+	j.username = username
+	j.incomingVersion = incomingVersion
+
+	for {
+		j.taskRunner.Log("Waiting for a runner")
+
+		select {
+		case j.taskRunner.pool.remoteJobs <- j:
+		case <-j.killed:
+			return fmt.Errorf("task stopped before a runner took it")
+		}
+
+		returned, err := j.wait()
+		if !returned {
+			return err
+		}
+	}
+}
+
+// wait waits until the runner which took the job finishes it.
+// It returns true if the runner did not receive the job, so it should be passed to another runner.
+func (j *RemoteJob) wait() (bool, error) {
+	timeout := time.Duration(util.Config.RunnerHeartbeatTimeout) * time.Second
+	heartbeat := time.NewTimer(timeout)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case res := <-j.finished:
+			if res.status != db.TaskSuccessStatus && res.unreachableHosts {
+				return false, fmt.Errorf("runner %d finished task with status %s: %w", j.getRunnerID(), res.status, lib.ErrUnreachableHosts)
+			}
+			if res.status != db.TaskSuccessStatus {
+				return false, fmt.Errorf("runner %d finished task with status %s", j.getRunnerID(), res.status)
+			}
+			return false, nil
+		case <-j.progress:
+			if !heartbeat.Stop() {
+				<-heartbeat.C
+			}
+			heartbeat.Reset(timeout)
+		case <-heartbeat.C:
+			// the runner crashed or lost connection to the server
+			return false, fmt.Errorf("runner %d did not report progress for %s", j.getRunnerID(), timeout)
+		case <-j.returned:
+			j.taskRunner.Log("The runner did not receive the task")
+			return true, nil
+		case <-j.killed:
+			return false, fmt.Errorf("task stopped")
+		}
+	}
+}
+
+func (j *RemoteJob) Kill() {
+	j.killOnce.Do(func() {
+		close(j.killed)
+	})
+}
+
+func (j *RemoteJob) getRunnerID() int {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	return j.runnerID
+}
+
+func (j *RemoteJob) setRunnerID(runnerID int) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.runnerID = runnerID
+}
+
+// heartbeat notifies the job that the runner reported its progress.
+func (j *RemoteJob) heartbeat() {
+	select {
+	case j.progress <- struct{}{}:
+	default:
+	}
+}
+
+// giveBack returns the job which the runner did not receive, so it is passed to another runner.
+func (j *RemoteJob) giveBack() {
+	j.setRunnerID(0)
+
+	select {
+	case j.returned <- struct{}{}:
+	default:
+	}
+}
+
+func (j *RemoteJob) finish(status db.TaskStatus, unreachableHosts bool) {
+	select {
+	case j.finished <- remoteJobResult{status: status, unreachableHosts: unreachableHosts}:
+	default:
+	}
+}
+
+func (j *RemoteJob) data() (data RemoteJobData, err error) {
+	t := j.taskRunner
+
+	data = RemoteJobData{
+		Username:        j.username,
+		IncomingVersion: j.incomingVersion,
+		Task:            t.task,
+		Template:        t.template,
+		Inventory:       t.inventory,
+		Repository:      t.repository,
+		Environment:     t.environment,
+		AccessKeys:      make(map[int]db.AccessKey),
+		BuildArtifacts:  t.buildArtifacts,
+
+		SecretSurveyValues: t.task.SecretSurveyValues,
+	}
+
+	keys := []db.AccessKey{t.repository.SSHKey}
+
+	if t.inventory.SSHKeyID != nil {
+		keys = append(keys, t.inventory.SSHKey)
+	}
+
+	if t.inventory.BecomeKeyID != nil {
+		keys = append(keys, t.inventory.BecomeKey)
+	}
+
+	if t.template.VaultKeyID != nil {
+		keys = append(keys, t.template.VaultKey)
+	}
+
+	for _, key := range keys {
+		err = key.DeserializeSecret()
+		if err != nil {
+			return
+		}
+		data.AccessKeys[key.ID] = key
+	}
+
+	return
+}
+
+// GetRunnerState waits up to timeout for a task which should be run by the runner.
+// Waiting is interrupted when cancel is closed, for example when the runner disconnects.
+// The caller must call ReturnRunnerState if it fails to send the state to the runner.
+func (p *TaskPool) GetRunnerState(runnerID int, timeout time.Duration, cancel <-chan struct{}) (state RunnerState) {
+	state.NewJobs = make([]RemoteJobData, 0)
+
+	select {
+	case j := <-p.remoteJobs:
+		// the runner could disconnect while the job was being received
+		select {
+		case <-cancel:
+			j.giveBack()
+			return
+		default:
+		}
+
+		j.setRunnerID(runnerID)
+
+		data, err := j.data()
+		if err != nil {
+			log.Error(err)
+			j.taskRunner.Log("Failed to pass task to runner: " + err.Error())
+			j.finish(db.TaskFailStatus, false)
+			return
+		}
+
+		j.taskRunner.Log("Task taken by runner " + strconv.Itoa(runnerID))
+		state.NewJobs = append(state.NewJobs, data)
+	case <-time.After(timeout):
+	case <-cancel:
+	}
+
+	return
+}
+
+// ReturnRunnerState passes jobs of the state, which the runner did not receive, to other runners.
+func (p *TaskPool) ReturnRunnerState(runnerID int, state RunnerState) {
+	for _, data := range state.NewJobs {
+		t, err := p.getRunnerTask(runnerID, data.Task.ID)
+		if err != nil {
+			continue
+		}
+
+		t.job.(*RemoteJob).giveBack()
+	}
+}
+
+func (p *TaskPool) getRunnerTask(runnerID int, taskID int) (*TaskRunner, error) {
+	t := p.GetTask(taskID)
+	if t == nil {
+		return nil, db.ErrNotFound
+	}
+
+	j, ok := t.job.(*RemoteJob)
+	if !ok || j.getRunnerID() != runnerID {
+		return nil, db.ErrNotFound
+	}
+
+	return t, nil
+}
+
+// AddRunnerArtifact stores a file produced by the task which the runner runs.
+func (p *TaskPool) AddRunnerArtifact(runnerID int, taskID int, name string, content io.Reader) error {
+	t, err := p.getRunnerTask(runnerID, taskID)
+	if err != nil {
+		return err
+	}
+
+	return t.AddArtifact(name, content)
+}
+
+// GetRunnerBuildArtifact returns content of an artifact of the build task
+// of the deploy task which the runner runs.
+func (p *TaskPool) GetRunnerBuildArtifact(runnerID int, taskID int, artifactID int) (io.ReadCloser, error) {
+	t, err := p.getRunnerTask(runnerID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.task.BuildTaskID == nil {
+		return nil, db.ErrNotFound
+	}
+
+	artifact, err := p.store.GetTaskArtifact(t.task.ProjectID, *t.task.BuildTaskID, artifactID)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.GetBuildArtifact(artifact)
+}
+
+// SetRunnerProgress stores output and state of tasks reported by the runner.
+func (p *TaskPool) SetRunnerProgress(runnerID int, progress RunnerProgress) (res RunnerProgressResult) {
+	res.Jobs = make([]RemoteJobState, 0)
+
+	for _, jp := range progress.Jobs {
+		t := p.GetTask(jp.ID)
+		if t == nil {
+			continue
+		}
+
+		j, ok := t.job.(*RemoteJob)
+		if !ok || j.getRunnerID() != runnerID {
+			continue
+		}
+
+		j.heartbeat()
+
+		for _, r := range jp.LogRecords {
+			t.LogWithTime(r.Time, r.Message)
+		}
+
+		if jp.CommitHash != nil {
+			t.SetCommit(*jp.CommitHash, jp.CommitMessage)
+		}
+
+		if len(jp.Hosts) > 0 {
+			t.SetHostResults(jp.Hosts)
+		}
+
+		if len(jp.Diffs) > 0 {
+			t.SetDiffs(jp.Diffs)
+		}
+
+		if len(jp.Annotations) > 0 {
+			t.SetAnnotations(jp.Annotations)
+		}
+
+		if jp.Version != nil {
+			t.SetVersion(*jp.Version)
+		}
+
+		switch jp.Status {
+		case db.TaskSuccessStatus, db.TaskFailStatus, db.TaskStoppedStatus:
+			j.finish(jp.Status, jp.UnreachableHosts)
+		}
+
+		res.Jobs = append(res.Jobs, RemoteJobState{
+			ID:     t.task.ID,
+			Status: t.task.Status,
+		})
+	}
+
+	return
+}
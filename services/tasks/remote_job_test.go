@@ -0,0 +1,149 @@
+package tasks
+
+import (
+	"github.com/ansible-semaphore/semaphore/db"
+	"github.com/ansible-semaphore/semaphore/util"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRemoteJobData_CreateLocalJob(t *testing.T) {
+	sshKeyID := 1
+	vaultKeyID := 3
+
+	data := RemoteJobData{
+		Task: db.Task{ID: 10},
+		Inventory: db.Inventory{
+			SSHKeyID: &sshKeyID,
+		},
+		Repository: db.Repository{
+			SSHKeyID: 2,
+		},
+		Template: db.Template{
+			VaultKeyID: &vaultKeyID,
+		},
+		AccessKeys: map[int]db.AccessKey{
+			1: {ID: 1, Type: db.AccessKeySSH, SshKey: db.SshKey{PrivateKey: "inventory"}},
+			2: {ID: 2, Type: db.AccessKeySSH, SshKey: db.SshKey{PrivateKey: "repository"}},
+			3: {ID: 3, Type: db.AccessKeyLoginPassword, LoginPassword: db.LoginPassword{Password: "vault"}},
+		},
+	}
+
+	job := data.CreateLocalJob(nil)
+
+	if job.Task.ID != 10 {
+		t.Fatal("task must be passed to the job")
+	}
+
+	if job.Inventory.SSHKey.SshKey.PrivateKey != "inventory" {
+		t.Fatal("inventory key must be attached")
+	}
+
+	if job.Repository.SSHKey.SshKey.PrivateKey != "repository" {
+		t.Fatal("repository key must be attached")
+	}
+
+	if job.Template.VaultKey.LoginPassword.Password != "vault" {
+		t.Fatal("vault key must be attached")
+	}
+
+	if job.Inventory.BecomeKey.ID != 0 {
+		t.Fatal("become key must be empty")
+	}
+}
+
+func createTestRemoteJob() *RemoteJob {
+	pool := CreateTaskPool(nil)
+
+	t := &TaskRunner{
+		task: db.Task{ID: 10, Status: db.TaskRunningStatus},
+		pool: &pool,
+		repository: db.Repository{
+			SSHKey: db.AccessKey{Type: db.AccessKeyNone},
+		},
+	}
+	t.job = createRemoteJob(t)
+
+	pool.runningTasks[t.task.ID] = t
+
+	return t.job.(*RemoteJob)
+}
+
+func TestRemoteJobHeartbeatTimeout(t *testing.T) {
+	oldConfig := util.Config
+	util.Config = &util.ConfigType{RunnerHeartbeatTimeout: 1}
+	defer func() { util.Config = oldConfig }()
+
+	j := createTestRemoteJob()
+	pool := j.taskRunner.pool
+
+	res := make(chan error, 1)
+	go func() {
+		res <- j.Run("", nil)
+	}()
+
+	state := pool.GetRunnerState(1, time.Second, nil)
+	if len(state.NewJobs) != 1 {
+		t.Fatal("runner must take the job")
+	}
+
+	progress := RunnerProgress{
+		Jobs: []RemoteJobProgress{{ID: 10, Status: db.TaskRunningStatus}},
+	}
+
+	for i := 0; i < 3; i++ {
+		time.Sleep(500 * time.Millisecond)
+		pool.SetRunnerProgress(1, progress)
+	}
+
+	select {
+	case err := <-res:
+		t.Fatal("job must not fail while the runner reports progress: ", err)
+	default:
+	}
+
+	select {
+	case err := <-res:
+		if err == nil || !strings.Contains(err.Error(), "did not report progress") {
+			t.Fatal("job must fail when the runner stops reporting progress: ", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("job must fail when the runner stops reporting progress")
+	}
+}
+
+func TestRemoteJobReturnRunnerState(t *testing.T) {
+	oldConfig := util.Config
+	util.Config = &util.ConfigType{RunnerHeartbeatTimeout: 60}
+	defer func() { util.Config = oldConfig }()
+
+	j := createTestRemoteJob()
+	pool := j.taskRunner.pool
+
+	res := make(chan error, 1)
+	go func() {
+		res <- j.Run("", nil)
+	}()
+	defer func() {
+		j.Kill()
+		<-res
+	}()
+
+	state := pool.GetRunnerState(1, time.Second, nil)
+	if len(state.NewJobs) != 1 {
+		t.Fatal("runner must take the job")
+	}
+
+	// the state could not be sent to the runner
+	pool.ReturnRunnerState(1, state)
+
+	if j.getRunnerID() != 0 {
+		t.Fatal("job must not belong to the runner which did not receive it")
+	}
+
+	state = pool.GetRunnerState(2, time.Second, nil)
+	if len(state.NewJobs) != 1 || j.getRunnerID() != 2 {
+		t.Fatal("job must be passed to another runner")
+	}
+}
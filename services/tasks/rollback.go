@@ -0,0 +1,83 @@
+package tasks
+
+import (
+	"strconv"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/ansible-semaphore/semaphore/db"
+)
+
+// AddRollbackTask creates a task of the deploy template which deploys a previous
+// successful build again. The task is pinned to the version and the commit of the build.
+func (p *TaskPool) AddRollbackTask(taskObj db.Task, tpl db.Template, userID *int) (newTask db.Task, err error) {
+	if tpl.Type != db.TemplateDeploy || tpl.BuildTemplateID == nil {
+		err = &db.ValidationError{Message: "only deploy templates with a build template can be redeployed"}
+		return
+	}
+
+	if taskObj.BuildTaskID == nil {
+		err = &db.ValidationError{Message: "build task is required"}
+		return
+	}
+
+	build, err := p.store.GetTask(tpl.ProjectID, *taskObj.BuildTaskID)
+	if err == db.ErrNotFound {
+		err = &db.ValidationError{Message: "build task not found"}
+	}
+	if err != nil {
+		return
+	}
+
+	if build.TemplateID != *tpl.BuildTemplateID || build.Status != db.TaskSuccessStatus || build.ValidateOnly {
+		err = &db.ValidationError{Message: "task " + strconv.Itoa(build.ID) + " is not a successful build of the build template"}
+		return
+	}
+
+	buildTpl, err := p.store.GetTemplate(tpl.ProjectID, build.TemplateID)
+	if err != nil {
+		return
+	}
+
+	taskObj.TemplateID = tpl.ID
+	taskObj.ProjectID = tpl.ProjectID
+	taskObj.BuildTaskID = &build.ID
+	taskObj.Version = taskObj.GetIncomingVersion(p.store)
+	taskObj.CommitHash = nil
+
+	// the commit of the build can be checked out only if the deploy uses the same repository
+	if buildTpl.RepositoryID == tpl.RepositoryID {
+		taskObj.CommitHash = build.CommitHash
+	}
+
+	target := "build " + strconv.Itoa(build.ID)
+	if taskObj.Version != nil {
+		target = "version " + *taskObj.Version + " (" + target + ")"
+	}
+
+	if taskObj.Message == "" {
+		taskObj.Message = "Rollback to " + target
+	}
+
+	newTask, err = p.AddTask(taskObj, userID, tpl.ProjectID)
+	if err != nil {
+		return
+	}
+
+	objType := db.EventTask
+	desc := "Task ID " + strconv.Itoa(newTask.ID) + " (" + tpl.Name + ") rolls back to " + target
+
+	_, err = p.store.CreateEvent(db.Event{
+		UserID:      userID,
+		ProjectID:   &tpl.ProjectID,
+		ObjectType:  &objType,
+		ObjectID:    &newTask.ID,
+		Description: &desc,
+	})
+
+	if err != nil {
+		log.Error(err)
+		err = nil
+	}
+
+	return
+}
@@ -1,12 +1,9 @@
 package tasks
 
 import (
-	"crypto/md5"
 	"encoding/json"
-	"fmt"
-	"github.com/ansible-semaphore/semaphore/lib"
+	"errors"
 	"io"
-	"io/ioutil"
 	"os"
 	"strconv"
 	"strings"
@@ -15,6 +12,7 @@ import (
 	log "github.com/Sirupsen/logrus"
 	"github.com/ansible-semaphore/semaphore/api/sockets"
 	"github.com/ansible-semaphore/semaphore/db"
+	"github.com/ansible-semaphore/semaphore/lib"
 	"github.com/ansible-semaphore/semaphore/util"
 )
 
@@ -28,33 +26,19 @@ type TaskRunner struct {
 	users     []int
 	alert     bool
 	alertChat *string
-	prepared  bool
-	process   *os.Process
 	pool      *TaskPool
-}
 
-func getMD5Hash(filepath string) (string, error) {
-	file, err := os.Open(filepath)
-	if err != nil {
-		return "", err
-	}
-	defer file.Close()
+	// job executes the task locally or passes it to a remote runner.
+	job Job
 
-	hash := md5.New()
-	if _, err := io.Copy(hash, file); err != nil {
-		return "", err
-	}
-	return fmt.Sprintf("%x", hash.Sum(nil)), nil
-}
+	// secrets masks secrets of the task in its output.
+	secrets secretMasker
 
-func (t *TaskRunner) getRepoPath() string {
-	repo := lib.GitRepository{
-		Logger:     t,
-		TemplateID: t.template.ID,
-		Repository: t.repository,
-	}
+	// buildArtifacts are files produced by the build task of the deploy task.
+	buildArtifacts []db.TaskArtifact
 
-	return repo.GetFullPath()
+	// retrying is true if a new attempt of the task is scheduled.
+	retrying bool
 }
 
 func (t *TaskRunner) setStatus(status db.TaskStatus) {
@@ -63,6 +47,7 @@ func (t *TaskRunner) setStatus(status db.TaskStatus) {
 		case db.TaskFailStatus:
 			status = db.TaskStoppedStatus
 		case db.TaskStoppedStatus:
+		case db.TaskTimeoutStatus:
 		default:
 			panic("stopping TaskRunner cannot be " + status)
 		}
@@ -72,11 +57,11 @@ func (t *TaskRunner) setStatus(status db.TaskStatus) {
 
 	t.updateStatus()
 
-	if status == db.TaskFailStatus {
+	if status == db.TaskFailStatus || status == db.TaskTimeoutStatus {
 		t.sendMailAlert()
 	}
 
-	if status == db.TaskSuccessStatus || status == db.TaskFailStatus {
+	if status == db.TaskSuccessStatus || status == db.TaskFailStatus || status == db.TaskTimeoutStatus {
 		t.sendTelegramAlert()
 	}
 }
@@ -104,120 +89,124 @@ func (t *TaskRunner) updateStatus() {
 	}
 }
 
+func (t *TaskRunner) priority() int {
+	return t.task.GetPriority(t.template)
+}
+
 func (t *TaskRunner) fail() {
 	t.setStatus(db.TaskFailStatus)
 }
 
-func (t *TaskRunner) destroyKeys() {
-	err := t.inventory.SSHKey.Destroy()
-	if err != nil {
-		t.Log("Can't destroy inventory user key, error: " + err.Error())
-	}
+// SetCommit stores the commit which the job checked out the repository to.
+func (t *TaskRunner) SetCommit(hash string, message string) {
+	t.task.CommitHash = &hash
+	t.task.CommitMessage = message
 
-	err = t.inventory.BecomeKey.Destroy()
-	if err != nil {
-		t.Log("Can't destroy inventory become user key, error: " + err.Error())
+	if err := t.pool.store.UpdateTask(t.task); err != nil {
+		t.panicOnError(err, "Failed to update TaskRunner commit")
 	}
+}
 
-	err = t.template.VaultKey.Destroy()
-	if err != nil {
-		t.Log("Can't destroy inventory vault password file, error: " + err.Error())
+// SetVersion stores the version of the build reported by the job.
+func (t *TaskRunner) SetVersion(version string) {
+	t.task.Version = &version
+	t.Log("Build version: " + version)
+	t.updateStatus()
+}
+
+// SetHostResults stores per-host results of the playbook.
+func (t *TaskRunner) SetHostResults(hosts []db.TaskHost) {
+	if err := t.pool.store.CreateTaskHosts(t.task.ID, hosts); err != nil {
+		t.Log("Failed to store host results: " + err.Error())
+		log.Error(err)
 	}
 }
 
-func (t *TaskRunner) createTaskEvent() {
-	objType := db.EventTask
-	desc := "Task ID " + strconv.Itoa(t.task.ID) + " (" + t.template.Name + ")" + " finished - " + strings.ToUpper(string(t.task.Status))
+// SetDiffs stores changes reported by the playbook.
+// Changed files can contain secrets, so they are masked like the output of the task.
+func (t *TaskRunner) SetDiffs(diffs []db.TaskDiff) {
+	masked := make([]db.TaskDiff, 0, len(diffs))
+	for _, diff := range diffs {
+		diff.TaskName = t.secrets.mask(diff.TaskName)
+		diff.BeforeHeader = t.secrets.mask(diff.BeforeHeader)
+		diff.Before = t.secrets.mask(diff.Before)
+		diff.AfterHeader = t.secrets.mask(diff.AfterHeader)
+		diff.After = t.secrets.mask(diff.After)
+		diff.Prepared = t.secrets.mask(diff.Prepared)
+		masked = append(masked, diff)
+	}
 
-	_, err := t.pool.store.CreateEvent(db.Event{
-		UserID:      t.task.UserID,
-		ProjectID:   &t.task.ProjectID,
-		ObjectType:  &objType,
-		ObjectID:    &t.task.ID,
-		Description: &desc,
-	})
+	if err := t.pool.store.CreateTaskDiffs(t.task.ID, masked); err != nil {
+		t.Log("Failed to store diffs: " + err.Error())
+		log.Error(err)
+	}
+}
 
-	if err != nil {
-		t.panicOnError(err, "Fatal error inserting an event")
+// SetAnnotations stores problems found by validation of the playbook.
+func (t *TaskRunner) SetAnnotations(annotations []db.TaskAnnotation) {
+	if err := t.pool.store.CreateTaskAnnotations(t.task.ID, annotations); err != nil {
+		t.Log("Failed to store annotations: " + err.Error())
+		log.Error(err)
 	}
 }
 
-func (t *TaskRunner) prepareRun() {
-	t.prepared = false
+// AddArtifact stores a file produced by the task.
+func (t *TaskRunner) AddArtifact(name string, content io.Reader) error {
+	size, err := lib.GetArtifactStore().Put(t.task.ID, name, content)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.pool.store.CreateTaskArtifact(db.TaskArtifact{
+		TaskID: t.task.ID,
+		Name:   name,
+		Size:   size,
+	})
 
-	defer func() {
-		log.Info("Stopped preparing TaskRunner " + strconv.Itoa(t.task.ID))
-		log.Info("Release resource locker with TaskRunner " + strconv.Itoa(t.task.ID))
-		t.pool.resourceLocker <- &resourceLock{lock: false, holder: t}
+	return err
+}
 
-		t.createTaskEvent()
-	}()
+// GetBuildArtifact returns content of an artifact of the build task.
+func (t *TaskRunner) GetBuildArtifact(artifact db.TaskArtifact) (io.ReadCloser, error) {
+	if t.task.BuildTaskID == nil || artifact.TaskID != *t.task.BuildTaskID {
+		return nil, db.ErrNotFound
+	}
 
-	t.Log("Preparing: " + strconv.Itoa(t.task.ID))
+	return lib.GetArtifactStore().Get(artifact.TaskID, artifact.Name)
+}
 
-	if err := checkTmpDir(util.Config.TmpPath); err != nil {
-		t.Log("Creating tmp dir failed: " + err.Error())
-		t.fail()
+func (t *TaskRunner) createJob() {
+	if util.Config.UseRemoteRunner {
+		t.job = createRemoteJob(t)
 		return
 	}
 
+	t.job = &LocalJob{
+		Task:           t.task,
+		Template:       t.template,
+		Inventory:      t.inventory,
+		Repository:     t.repository,
+		Environment:    t.environment,
+		BuildArtifacts: t.buildArtifacts,
+		Logger:         t,
+	}
+}
+
+func (t *TaskRunner) createTaskEvent() {
 	objType := db.EventTask
-	desc := "Task ID " + strconv.Itoa(t.task.ID) + " (" + t.template.Name + ")" + " is preparing"
-	evt := db.Event{
+	desc := "Task ID " + strconv.Itoa(t.task.ID) + " (" + t.template.Name + ")" + " finished - " + strings.ToUpper(string(t.task.Status))
+
+	_, err := t.pool.store.CreateEvent(db.Event{
 		UserID:      t.task.UserID,
 		ProjectID:   &t.task.ProjectID,
 		ObjectType:  &objType,
 		ObjectID:    &t.task.ID,
 		Description: &desc,
-	}
-
-	if _, err := t.pool.store.CreateEvent(evt); err != nil {
-		t.Log("Fatal error inserting an event")
-		panic(err)
-	}
-
-	t.Log("Prepare TaskRunner with template: " + t.template.Name + "\n")
-
-	t.updateStatus()
-
-	if t.repository.GetType() == db.RepositoryLocal {
-		if _, err := os.Stat(t.repository.GitURL); err != nil {
-			t.Log("Failed in finding static repository at " + t.repository.GitURL + ": " + err.Error())
-			t.fail()
-			return
-		}
-	} else {
-		if err := t.updateRepository(); err != nil {
-			t.Log("Failed updating repository: " + err.Error())
-			t.fail()
-			return
-		}
-		if err := t.checkoutRepository(); err != nil {
-			t.Log("Failed to checkout repository to required commit: " + err.Error())
-			t.fail()
-			return
-		}
-	}
-
-	if err := t.installInventory(); err != nil {
-		t.Log("Failed to install inventory: " + err.Error())
-		t.fail()
-		return
-	}
-
-	if err := t.installRequirements(); err != nil {
-		t.Log("Running galaxy failed: " + err.Error())
-		t.fail()
-		return
-	}
+	})
 
-	if err := t.installVaultKeyFile(); err != nil {
-		t.Log("Failed to install vault password file: " + err.Error())
-		t.fail()
-		return
+	if err != nil {
+		t.panicOnError(err, "Fatal error inserting an event")
 	}
-
-	t.prepared = true
 }
 
 func (t *TaskRunner) run() {
@@ -230,7 +219,11 @@ func (t *TaskRunner) run() {
 		t.task.End = &now
 		t.updateStatus()
 		t.createTaskEvent()
-		t.destroyKeys()
+
+		// the pipeline continues when the last attempt of the task is finished
+		if !t.retrying {
+			t.pool.onTaskFinished(t.task)
+		}
 	}()
 
 	// TODO: more details
@@ -268,15 +261,44 @@ func (t *TaskRunner) run() {
 		return
 	}
 
-	err = t.runPlaybook()
+	var username string
+	if t.task.UserID != nil {
+		var user db.User
+		user, err = t.pool.store.GetUser(*t.task.UserID)
+		if err == nil {
+			username = user.Username
+		}
+	}
+
+	var timer *time.Timer
+	timeout := t.task.GetTimeout(t.template)
+	if timeout > 0 {
+		// the job is killed in the same way as TaskPool.StopTask does it
+		timer = time.AfterFunc(timeout, t.job.Kill)
+	}
+
+	err = t.job.Run(username, t.task.GetIncomingVersion(t.pool.store))
+
+	if timer != nil && !timer.Stop() {
+		t.Log("Task exceeded maximum runtime of " + timeout.String() + " and was terminated")
+		t.setStatus(db.TaskTimeoutStatus)
+		return
+	}
+
 	if err != nil {
 		t.Log("Running playbook failed: " + err.Error())
 		t.fail()
+		t.retry(err)
 		return
 	}
 
 	t.setStatus(db.TaskSuccessStatus)
 
+	// next stages of pipelines are started by the pipeline
+	if t.task.PipelineRunID != nil || t.task.ValidateOnly {
+		return
+	}
+
 	templates, err := t.pool.store.GetTemplates(t.task.ProjectID, db.TemplateFilter{
 		BuildTemplateID: &t.task.TemplateID,
 		AutorunOnly:     true,
@@ -299,6 +321,76 @@ func (t *TaskRunner) run() {
 	}
 }
 
+// retry queues a new attempt of the failed task if the template retry policy allows it.
+func (t *TaskRunner) retry(err error) {
+	if t.task.Status != db.TaskFailStatus {
+		return
+	}
+
+	attempt := t.task.GetAttempt()
+
+	if attempt > t.template.RetryCount {
+		return
+	}
+
+	if t.template.RetryOn == db.TemplateRetryOnUnreachable && !lib.IsUnreachableHostsError(err) {
+		return
+	}
+
+	if errors.Is(err, errPlaybookValidation) {
+		return
+	}
+
+	retryOf := t.task.ID
+	if t.task.RetryOfTaskID != nil {
+		retryOf = *t.task.RetryOfTaskID
+	}
+
+	newTask := db.Task{
+		TemplateID:    t.task.TemplateID,
+		Debug:         t.task.Debug,
+		DryRun:        t.task.DryRun,
+		Diff:          t.task.Diff,
+		ValidateOnly:  t.task.ValidateOnly,
+		Playbook:      t.task.Playbook,
+		Environment:   t.task.Environment,
+		Limit:         t.task.Limit,
+		Message:       t.task.Message,
+		CommitHash:    t.task.CommitHash,
+		BuildTaskID:   t.task.BuildTaskID,
+		Version:       t.task.Version,
+		Arguments:     t.task.Arguments,
+		Timeout:       t.task.Timeout,
+		Priority:      t.task.Priority,
+		SurveyValues:  t.task.SurveyValues,
+		Attempt:       attempt + 1,
+		RetryOfTaskID: &retryOf,
+		SkipApproval:  true,
+		PipelineRunID: t.task.PipelineRunID,
+		PipelineStage: t.task.PipelineStage,
+
+		// secret values are not stored, so they are taken from the current run
+		SecretSurveyValues: t.task.SecretSurveyValues,
+	}
+
+	delay := time.Duration(t.template.RetryDelay) * time.Second
+
+	t.Log("Task will be retried in " + delay.String() +
+		" (attempt " + strconv.Itoa(attempt+1) + " of " + strconv.Itoa(t.template.RetryCount+1) + ")")
+
+	t.retrying = true
+
+	go func() {
+		time.Sleep(delay)
+
+		_, err := t.pool.AddTask(newTask, t.task.UserID, t.task.ProjectID)
+		if err != nil {
+			log.Error(err)
+			t.pool.onTaskFinished(t.task)
+		}
+	}()
+}
+
 func (t *TaskRunner) prepareError(err error, errMsg string) error {
 	if err == db.ErrNotFound {
 		t.Log(errMsg)
@@ -332,6 +424,15 @@ func (t *TaskRunner) populateDetails() error {
 	t.alert = project.Alert
 	t.alertChat = project.AlertChat
 
+	t.secrets = secretMasker{}
+	for _, pattern := range project.LogMaskPatterns {
+		err = t.secrets.addPattern(pattern)
+		if err != nil {
+			t.Log("Invalid log mask pattern " + pattern)
+			return err
+		}
+	}
+
 	// get project users
 	users, err := t.pool.store.GetProjectUsers(t.template.ProjectID, db.RetrieveQueryParams{})
 	if err != nil {
@@ -397,370 +498,43 @@ func (t *TaskRunner) populateDetails() error {
 		t.environment.JSON = string(ev)
 	}
 
-	return nil
-}
-
-func (t *TaskRunner) installVaultKeyFile() error {
-	if t.template.VaultKeyID == nil {
-		return nil
-	}
-
-	return t.template.VaultKey.Install(db.AccessKeyRoleAnsiblePasswordVault)
-}
-
-func (t *TaskRunner) checkoutRepository() error {
-
-	repo := lib.GitRepository{
-		Logger:     t,
-		TemplateID: t.template.ID,
-		Repository: t.repository,
-	}
-
-	err := repo.ValidateRepo()
-
-	if err != nil {
-		return err
-	}
-
-	if t.task.CommitHash != nil {
-		// checkout to commit if it is provided for TaskRunner
-		return repo.Checkout(*t.task.CommitHash)
-	}
-
-	// store commit to TaskRunner table
-
-	commitHash, err := repo.GetLastCommitHash()
-
-	if err != nil {
-		return err
-	}
-
-	commitMessage, _ := repo.GetLastCommitMessage()
-
-	t.task.CommitHash = &commitHash
-	t.task.CommitMessage = commitMessage
-
-	return t.pool.store.UpdateTask(t.task)
-}
-
-func (t *TaskRunner) updateRepository() error {
-	repo := lib.GitRepository{
-		Logger:     t,
-		TemplateID: t.template.ID,
-		Repository: t.repository,
-	}
-
-	err := repo.ValidateRepo()
-
-	if err != nil {
-		if !os.IsNotExist(err) {
-			err = os.RemoveAll(repo.GetFullPath())
-			if err != nil {
-				return err
-			}
-		}
-		return repo.Clone()
-	}
-
-	if repo.CanBePulled() {
-		err = repo.Pull()
-		if err == nil {
-			return nil
-		}
-	}
-
-	err = os.RemoveAll(repo.GetFullPath())
-	if err != nil {
-		return err
-	}
-
-	return repo.Clone()
-}
-
-func (t *TaskRunner) installCollectionsRequirements() error {
-	requirementsFilePath := fmt.Sprintf("%s/collections/requirements.yml", t.getRepoPath())
-	requirementsHashFilePath := fmt.Sprintf("%s.md5", requirementsFilePath)
-
-	if _, err := os.Stat(requirementsFilePath); err != nil {
-		t.Log("No collections/requirements.yml file found. Skip galaxy install process.\n")
-		return nil
-	}
-
-	if hasRequirementsChanges(requirementsFilePath, requirementsHashFilePath) {
-		if err := t.runGalaxy([]string{
-			"collection",
-			"install",
-			"-r",
-			requirementsFilePath,
-			"--force",
-		}); err != nil {
+	if t.task.BuildTaskID != nil {
+		t.buildArtifacts, err = t.pool.store.GetTaskArtifacts(t.task.ProjectID, *t.task.BuildTaskID)
+		if err != nil && err != db.ErrNotFound {
 			return err
 		}
-		if err := writeMD5Hash(requirementsFilePath, requirementsHashFilePath); err != nil {
-			return err
-		}
-	} else {
-		t.Log("collections/requirements.yml has no changes. Skip galaxy install process.\n")
 	}
 
-	return nil
+	return t.collectSecrets()
 }
 
-func (t *TaskRunner) installRolesRequirements() error {
-	requirementsFilePath := fmt.Sprintf("%s/roles/requirements.yml", t.getRepoPath())
-	requirementsHashFilePath := fmt.Sprintf("%s.md5", requirementsFilePath)
-
-	if _, err := os.Stat(requirementsFilePath); err != nil {
-		t.Log("No roles/requirements.yml file found. Skip galaxy install process.\n")
-		return nil
+// collectSecrets registers secrets which will be installed for the task in the masker.
+func (t *TaskRunner) collectSecrets() error {
+	keys := []db.AccessKey{
+		t.inventory.SSHKey,
+		t.inventory.BecomeKey,
+		t.repository.SSHKey,
+		t.template.VaultKey,
 	}
 
-	if hasRequirementsChanges(requirementsFilePath, requirementsHashFilePath) {
-		if err := t.runGalaxy([]string{
-			"role",
-			"install",
-			"-r",
-			requirementsFilePath,
-			"--force",
-		}); err != nil {
-			return err
-		}
-		if err := writeMD5Hash(requirementsFilePath, requirementsHashFilePath); err != nil {
+	for _, key := range keys {
+		if err := t.secrets.addAccessKey(key); err != nil {
+			t.Log("Failed to read access key " + strconv.Itoa(key.ID))
 			return err
 		}
-	} else {
-		t.Log("roles/requirements.yml has no changes. Skip galaxy install process.\n")
-	}
-
-	return nil
-}
-
-func (t *TaskRunner) installRequirements() error {
-	if err := t.installCollectionsRequirements(); err != nil {
-		return err
-	}
-	if err := t.installRolesRequirements(); err != nil {
-		return err
-	}
-	return nil
-}
-
-func (t *TaskRunner) runGalaxy(args []string) error {
-	return lib.AnsiblePlaybook{
-		Logger:     t,
-		TemplateID: t.template.ID,
-		Repository: t.repository,
-	}.RunGalaxy(args)
-}
-
-func (t *TaskRunner) runPlaybook() (err error) {
-	args, err := t.getPlaybookArgs()
-	if err != nil {
-		return
-	}
-
-	environmentVariables, err := t.getEnvironmentENV()
-	if err != nil {
-		return
-	}
-
-	return lib.AnsiblePlaybook{
-		Logger:     t,
-		TemplateID: t.template.ID,
-		Repository: t.repository,
-	}.RunPlaybook(args, &environmentVariables, func(p *os.Process) { t.process = p })
-}
-
-func (t *TaskRunner) getEnvironmentENV() (arr []string, err error) {
-	environmentVars := make(map[string]string)
-
-	if t.environment.ENV != nil {
-		err = json.Unmarshal([]byte(*t.environment.ENV), &environmentVars)
-		if err != nil {
-			return
-		}
-	}
-
-	for key, val := range environmentVars {
-		arr = append(arr, fmt.Sprintf("%s=%s", key, val))
-	}
-
-	return
-}
-
-func (t *TaskRunner) getEnvironmentExtraVars() (str string, err error) {
-	extraVars := make(map[string]interface{})
-
-	if t.environment.JSON != "" {
-		err = json.Unmarshal([]byte(t.environment.JSON), &extraVars)
-		if err != nil {
-			return
-		}
 	}
 
-	taskDetails := make(map[string]interface{})
-
-	if t.task.Message != "" {
-		taskDetails["message"] = t.task.Message
+	if t.environment.Password != nil {
+		t.secrets.addSecret(*t.environment.Password)
 	}
 
-	if t.task.UserID != nil {
-		var user db.User
-		user, err = t.pool.store.GetUser(*t.task.UserID)
-		if err == nil {
-			taskDetails["username"] = user.Username
+	for _, value := range t.task.SecretSurveyValues {
+		if secret, ok := value.(string); ok {
+			t.secrets.addSurveySecret(secret)
 		}
 	}
 
-	if t.template.Type != db.TemplateTask {
-		taskDetails["type"] = t.template.Type
-		incomingVersion := t.task.GetIncomingVersion(t.pool.store)
-		if incomingVersion != nil {
-			taskDetails["incoming_version"] = incomingVersion
-		}
-		if t.template.Type == db.TemplateBuild {
-			taskDetails["target_version"] = t.task.Version
-		}
-	}
-
-	vars := make(map[string]interface{})
-	vars["task_details"] = taskDetails
-	extraVars["semaphore_vars"] = vars
-
-	ev, err := json.Marshal(extraVars)
-	if err != nil {
-		return
-	}
-
-	str = string(ev)
-
-	return
-}
-
-//nolint: gocyclo
-func (t *TaskRunner) getPlaybookArgs() (args []string, err error) {
-	playbookName := t.task.Playbook
-	if playbookName == "" {
-		playbookName = t.template.Playbook
-	}
-
-	var inventory string
-	switch t.inventory.Type {
-	case db.InventoryFile:
-		inventory = t.inventory.Inventory
-	case db.InventoryStatic, db.InventoryStaticYaml:
-		inventory = util.Config.TmpPath + "/inventory_" + strconv.Itoa(t.task.ID)
-		if t.inventory.Type == db.InventoryStaticYaml {
-			inventory += ".yml"
-		}
-	default:
-		err = fmt.Errorf("invalid invetory type")
-		return
-	}
-
-	args = []string{
-		"-i", inventory,
-	}
-
-	if t.inventory.SSHKeyID != nil {
-		switch t.inventory.SSHKey.Type {
-		case db.AccessKeySSH:
-			args = append(args, "--private-key="+t.inventory.SSHKey.GetPath())
-			//args = append(args, "--extra-vars={\"ansible_ssh_private_key_file\": \""+t.inventory.SSHKey.GetPath()+"\"}")
-			if t.inventory.SSHKey.SshKey.Login != "" {
-				args = append(args, "--extra-vars={\"ansible_user\": \""+t.inventory.SSHKey.SshKey.Login+"\"}")
-			}
-		case db.AccessKeyLoginPassword:
-			args = append(args, "--extra-vars=@"+t.inventory.SSHKey.GetPath())
-		case db.AccessKeyNone:
-		default:
-			err = fmt.Errorf("access key does not suite for inventory's user credentials")
-			return
-		}
-	}
-
-	if t.inventory.BecomeKeyID != nil {
-		switch t.inventory.BecomeKey.Type {
-		case db.AccessKeyLoginPassword:
-			args = append(args, "--extra-vars=@"+t.inventory.BecomeKey.GetPath())
-		case db.AccessKeyNone:
-		default:
-			err = fmt.Errorf("access key does not suite for inventory's sudo user credentials")
-			return
-		}
-	}
-
-	if t.task.Debug {
-		args = append(args, "-vvvv")
-	}
-
-	if t.task.DryRun {
-		args = append(args, "--check")
-	}
-
-	if t.template.VaultKeyID != nil {
-		args = append(args, "--vault-password-file", t.template.VaultKey.GetPath())
-	}
-
-	extraVars, err := t.getEnvironmentExtraVars()
-	if err != nil {
-		t.Log(err.Error())
-		t.Log("Could not remove command environment, if existant it will be passed to --extra-vars. This is not fatal but be aware of side effects")
-	} else if extraVars != "" {
-		args = append(args, "--extra-vars", extraVars)
-	}
-
-	var templateExtraArgs []string
-	if t.template.Arguments != nil {
-		err = json.Unmarshal([]byte(*t.template.Arguments), &templateExtraArgs)
-		if err != nil {
-			t.Log("Invalid format of the template extra arguments, must be valid JSON")
-			return
-		}
-	}
-
-	var taskExtraArgs []string
-	if t.template.AllowOverrideArgsInTask && t.task.Arguments != nil {
-		err = json.Unmarshal([]byte(*t.task.Arguments), &taskExtraArgs)
-		if err != nil {
-			t.Log("Invalid format of the TaskRunner extra arguments, must be valid JSON")
-			return
-		}
-	}
-
-	if t.task.Limit != "" {
-		t.Log("--limit=" + t.task.Limit)
-		taskExtraArgs = append(taskExtraArgs, "--limit="+t.task.Limit)
-	}
-
-	args = append(args, templateExtraArgs...)
-	args = append(args, taskExtraArgs...)
-	args = append(args, playbookName)
-
-	return
-}
-
-func hasRequirementsChanges(requirementsFilePath string, requirementsHashFilePath string) bool {
-	oldFileMD5HashBytes, err := ioutil.ReadFile(requirementsHashFilePath)
-	if err != nil {
-		return true
-	}
-
-	newFileMD5Hash, err := getMD5Hash(requirementsFilePath)
-	if err != nil {
-		return true
-	}
-
-	return string(oldFileMD5HashBytes) != newFileMD5Hash
-}
-
-func writeMD5Hash(requirementsFile string, requirementsHashFile string) error {
-	newFileMD5Hash, err := getMD5Hash(requirementsFile)
-	if err != nil {
-		return err
-	}
-
-	return ioutil.WriteFile(requirementsHashFile, []byte(newFileMD5Hash), 0644)
+	return nil
 }
 
 // checkTmpDir checks to see if the temporary directory exists
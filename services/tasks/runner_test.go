@@ -3,7 +3,9 @@ package tasks
 import (
 	"github.com/ansible-semaphore/semaphore/db"
 	"github.com/ansible-semaphore/semaphore/db/bolt"
+	"github.com/ansible-semaphore/semaphore/lib"
 	"github.com/ansible-semaphore/semaphore/util"
+	"io/ioutil"
 	"math/rand"
 	"os"
 	"path"
@@ -104,9 +106,9 @@ func TestTaskGetPlaybookArgs(t *testing.T) {
 
 	inventoryID := 1
 
-	tsk := TaskRunner{
-		task: db.Task{},
-		inventory: db.Inventory{
+	tsk := LocalJob{
+		Task: db.Task{},
+		Inventory: db.Inventory{
 			SSHKeyID: &inventoryID,
 			SSHKey: db.AccessKey{
 				ID:   12345,
@@ -114,7 +116,7 @@ func TestTaskGetPlaybookArgs(t *testing.T) {
 			},
 			Type: db.InventoryStatic,
 		},
-		template: db.Template{
+		Template: db.Template{
 			Playbook: "test.yml",
 		},
 	}
@@ -126,7 +128,7 @@ func TestTaskGetPlaybookArgs(t *testing.T) {
 	}
 
 	res := strings.Join(args, " ")
-	if res != "-i /tmp/inventory_0 --private-key=/tmp/access_key_0 --extra-vars {\"semaphore_vars\":{\"task_details\":{}}} test.yml" {
+	if res != "-i /tmp/inventory_0 --private-key=/tmp/access_key_0 --extra-vars {\"semaphore_vars\":{\"task_details\":{\"attempt\":1}}} test.yml" {
 		t.Fatal("incorrect result")
 	}
 }
@@ -138,9 +140,9 @@ func TestTaskGetPlaybookArgs2(t *testing.T) {
 
 	inventoryID := 1
 
-	tsk := TaskRunner{
-		task: db.Task{},
-		inventory: db.Inventory{
+	tsk := LocalJob{
+		Task: db.Task{},
+		Inventory: db.Inventory{
 			Type:     db.InventoryStatic,
 			SSHKeyID: &inventoryID,
 			SSHKey: db.AccessKey{
@@ -152,7 +154,7 @@ func TestTaskGetPlaybookArgs2(t *testing.T) {
 				},
 			},
 		},
-		template: db.Template{
+		Template: db.Template{
 			Playbook: "test.yml",
 		},
 	}
@@ -164,7 +166,7 @@ func TestTaskGetPlaybookArgs2(t *testing.T) {
 	}
 
 	res := strings.Join(args, " ")
-	if res != "-i /tmp/inventory_0 --extra-vars=@/tmp/access_key_0 --extra-vars {\"semaphore_vars\":{\"task_details\":{}}} test.yml" {
+	if res != "-i /tmp/inventory_0 --extra-vars=@/tmp/access_key_0 --extra-vars {\"semaphore_vars\":{\"task_details\":{\"attempt\":1}}} test.yml" {
 		t.Fatal("incorrect result")
 	}
 }
@@ -176,9 +178,9 @@ func TestTaskGetPlaybookArgs3(t *testing.T) {
 
 	inventoryID := 1
 
-	tsk := TaskRunner{
-		task: db.Task{},
-		inventory: db.Inventory{
+	tsk := LocalJob{
+		Task: db.Task{},
+		Inventory: db.Inventory{
 			Type:        db.InventoryStatic,
 			BecomeKeyID: &inventoryID,
 			BecomeKey: db.AccessKey{
@@ -190,7 +192,7 @@ func TestTaskGetPlaybookArgs3(t *testing.T) {
 				},
 			},
 		},
-		template: db.Template{
+		Template: db.Template{
 			Playbook: "test.yml",
 		},
 	}
@@ -202,11 +204,119 @@ func TestTaskGetPlaybookArgs3(t *testing.T) {
 	}
 
 	res := strings.Join(args, " ")
-	if res != "-i /tmp/inventory_0 --extra-vars=@/tmp/access_key_0 --extra-vars {\"semaphore_vars\":{\"task_details\":{}}} test.yml" {
+	if res != "-i /tmp/inventory_0 --extra-vars=@/tmp/access_key_0 --extra-vars {\"semaphore_vars\":{\"task_details\":{\"attempt\":1}}} test.yml" {
 		t.Fatal("incorrect result")
 	}
 }
 
+func TestTaskGetPlaybookArgsSecretVars(t *testing.T) {
+	tmpPath, err := ioutil.TempDir("", "semaphore_secret_vars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpPath) //nolint: errcheck
+
+	oldConfig := util.Config
+	util.Config = &util.ConfigType{
+		TmpPath: tmpPath,
+	}
+	defer func() { util.Config = oldConfig }()
+
+	tsk := LocalJob{
+		Task: db.Task{
+			ID:                 1,
+			SurveyValues:       map[string]interface{}{"user": "deploy"},
+			SecretSurveyValues: map[string]interface{}{"password": "top-secret"},
+		},
+		Inventory: db.Inventory{
+			Type: db.InventoryStatic,
+		},
+		Template: db.Template{
+			Playbook: "test.yml",
+		},
+	}
+
+	if err = os.MkdirAll(tsk.getCallbackDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tsk.installSecretVars(); err != nil {
+		t.Fatal(err)
+	}
+
+	args, err := tsk.getPlaybookArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := strings.Join(args, " ")
+	if strings.Contains(res, "top-secret") {
+		t.Fatal("secret values must not be passed in arguments")
+	}
+
+	if !strings.Contains(res, "--extra-vars=@"+tsk.getSecretVarsPath()) {
+		t.Fatal("secret values must be passed in the file", res)
+	}
+
+	info, err := os.Stat(tsk.getSecretVarsPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0600 {
+		t.Fatal("file with secret values must be readable only by the owner")
+	}
+
+	content, err := ioutil.ReadFile(tsk.getSecretVarsPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(content) != "{\"password\":\"top-secret\"}" {
+		t.Fatal("unexpected content of the file with secret values: " + string(content))
+	}
+}
+
+func TestGetExecutorContainerHomeDir(t *testing.T) {
+	tmpPath, err := ioutil.TempDir("", "semaphore_container_home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpPath) //nolint: errcheck
+
+	oldConfig := util.Config
+	util.Config = &util.ConfigType{
+		TmpPath: tmpPath,
+	}
+	defer func() { util.Config = oldConfig }()
+
+	tpl := db.Template{ID: 1, Playbook: "test.yml", ContainerImage: "ansible:latest"}
+
+	job1 := LocalJob{Task: db.Task{ID: 1}, Template: tpl, Logger: &testJobLogger{}}
+	job2 := LocalJob{Task: db.Task{ID: 2}, Template: tpl, Logger: &testJobLogger{}}
+
+	executor1, ok := job1.getExecutor().(lib.ContainerExecutor)
+	if !ok {
+		t.Fatal("template with the image must run in the container")
+	}
+
+	executor2 := job2.getExecutor().(lib.ContainerExecutor)
+
+	if executor1.HomeDir == executor2.HomeDir {
+		t.Fatal("tasks of the same template must not share the home directory")
+	}
+
+	job1.destroyContainerHomeDir()
+
+	if _, err = os.Stat(executor1.HomeDir); !os.IsNotExist(err) {
+		t.Fatal("home directory must be removed after the task")
+	}
+
+	if _, err = os.Stat(executor2.HomeDir); err != nil {
+		t.Fatal("home directory of other task must be kept")
+	}
+}
+
 func TestCheckTmpDir(t *testing.T) {
 	//It should be able to create a random dir in /tmp
 	dirName := path.Join(os.TempDir(), util.RandString(rand.Intn(10-4)+4))
@@ -0,0 +1,153 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ansible-semaphore/semaphore/db"
+)
+
+// errPlaybookValidation is returned when pre-flight validation of the playbook fails.
+// Such tasks are not retried because the next attempt fails in the same way.
+var errPlaybookValidation = errors.New("playbook validation failed")
+
+var ansiEscapeRegex = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+var syntaxErrorRegex = regexp.MustCompile(`(?m)^(?:ERROR!|\[ERROR\]:)\s*(.+)$`)
+
+var syntaxErrorLocationRegex = regexp.MustCompile(`The error appears to be in '([^']+)': line (\d+)`)
+
+// lintProblemRegex matches problems reported by linters in the parseable format
+// "file:line[:column]: [rule] message" or "file:line[:column]: rule-id message".
+var lintProblemRegex = regexp.MustCompile(
+	`^([^:\s]+):(\d+)(?::\d+)?:\s*(?:\[([^\]]+)\]\s*|([a-z0-9]+(?:-[a-z0-9]+)+|[\w-]+\[[\w-]+\]):?\s+)?(.+)$`)
+
+// validatePlaybook runs the syntax check and the lint command of the template
+// before the playbook and stores problems found by them as annotations of the task.
+func (t *LocalJob) validatePlaybook() error {
+	syntaxCheck := t.Template.App == db.TemplateAnsible && (t.Template.SyntaxCheck || t.Task.ValidateOnly)
+
+	if !syntaxCheck && t.Template.LintCommand == "" {
+		return nil
+	}
+
+	environmentVariables, err := t.getEnvironmentENV()
+	if err != nil {
+		return err
+	}
+
+	var annotations []db.TaskAnnotation
+	var reasons []string
+
+	if syntaxCheck {
+		var args []string
+		args, err = t.getPlaybookArgs()
+		if err != nil {
+			return err
+		}
+
+		t.Logger.Log("Checking playbook syntax")
+
+		var output string
+		output, err = t.getAnsiblePlaybook().RunCheck("ansible-playbook", append([]string{"--syntax-check"}, args...), &environmentVariables)
+		if t.isKilled() {
+			return fmt.Errorf("task stopped")
+		}
+
+		if err != nil {
+			annotations = append(annotations, parseSyntaxCheckOutput(output, t.getRepoPath()))
+			reasons = append(reasons, "syntax check failed")
+		}
+	}
+
+	if t.Template.LintCommand != "" {
+		command := strings.Fields(t.Template.LintCommand)
+
+		t.Logger.Log("Running lint command: " + t.Template.LintCommand)
+
+		var output string
+		output, err = t.getAnsiblePlaybook().RunCheck(command[0], command[1:], &environmentVariables)
+		if t.isKilled() {
+			return fmt.Errorf("task stopped")
+		}
+
+		problems := parseLintOutput(output, t.getRepoPath())
+		annotations = append(annotations, problems...)
+
+		if err != nil {
+			if len(problems) > 0 {
+				reasons = append(reasons, "lint found "+strconv.Itoa(len(problems))+" problem(s)")
+			} else {
+				reasons = append(reasons, "lint command failed: "+err.Error())
+			}
+		}
+	}
+
+	if len(annotations) > 0 {
+		t.Logger.SetAnnotations(annotations)
+	}
+
+	if len(reasons) > 0 {
+		return fmt.Errorf("%w: %s", errPlaybookValidation, strings.Join(reasons, ", "))
+	}
+
+	return nil
+}
+
+// parseSyntaxCheckOutput returns the error reported by ansible-playbook --syntax-check.
+func parseSyntaxCheckOutput(output string, repoPath string) db.TaskAnnotation {
+	output = ansiEscapeRegex.ReplaceAllString(output, "")
+
+	annotation := db.TaskAnnotation{
+		Source:  db.TaskAnnotationSyntaxCheck,
+		Message: "syntax check failed",
+	}
+
+	if m := syntaxErrorRegex.FindStringSubmatch(output); m != nil {
+		annotation.Message = strings.TrimSpace(m[1])
+	}
+
+	if m := syntaxErrorLocationRegex.FindStringSubmatch(output); m != nil {
+		annotation.File = getRepoRelativePath(m[1], repoPath)
+		annotation.Line, _ = strconv.Atoi(m[2])
+	}
+
+	return annotation
+}
+
+// parseLintOutput returns problems reported by the lint command in the parseable format.
+// Lines in other formats are ignored.
+func parseLintOutput(output string, repoPath string) (annotations []db.TaskAnnotation) {
+	output = ansiEscapeRegex.ReplaceAllString(output, "")
+
+	for _, line := range strings.Split(output, "\n") {
+		m := lintProblemRegex.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+
+		lineNum, _ := strconv.Atoi(m[2])
+
+		rule := m[3]
+		if rule == "" {
+			rule = m[4]
+		}
+
+		annotations = append(annotations, db.TaskAnnotation{
+			Source:  db.TaskAnnotationLint,
+			File:    getRepoRelativePath(m[1], repoPath),
+			Line:    lineNum,
+			Rule:    rule,
+			Message: strings.TrimSpace(m[5]),
+		})
+	}
+
+	return
+}
+
+func getRepoRelativePath(file string, repoPath string) string {
+	return strings.TrimPrefix(file, strings.TrimSuffix(repoPath, "/")+"/")
+}
@@ -0,0 +1,59 @@
+package tasks
+
+import (
+	"testing"
+
+	"github.com/ansible-semaphore/semaphore/db"
+)
+
+func TestParseSyntaxCheckOutput(t *testing.T) {
+	output := "\x1b[0;31mERROR! conflicting action statements: debug, command\x1b[0m\n\n" +
+		"The error appears to be in '/tmp/semaphore/repository_1_task_5/roles/web/tasks/main.yml': " +
+		"line 4, column 7, but may\nbe elsewhere in the file depending on the exact syntax problem.\n"
+
+	annotation := parseSyntaxCheckOutput(output, "/tmp/semaphore/repository_1_task_5")
+
+	if annotation.Source != db.TaskAnnotationSyntaxCheck {
+		t.Fatal("unexpected source", annotation.Source)
+	}
+
+	if annotation.File != "roles/web/tasks/main.yml" || annotation.Line != 4 {
+		t.Fatal("unexpected location", annotation.File, annotation.Line)
+	}
+
+	if annotation.Message != "conflicting action statements: debug, command" {
+		t.Fatal("unexpected message", annotation.Message)
+	}
+
+	annotation = parseSyntaxCheckOutput("unexpected failure", "/tmp")
+	if annotation.Message != "syntax check failed" || annotation.File != "" {
+		t.Fatal("unknown output must be reported as a failed syntax check", annotation)
+	}
+}
+
+func TestParseLintOutput(t *testing.T) {
+	output := "WARNING  Listing 3 violation(s) that are fatal\n" +
+		"site.yml:5:1: name[missing] All tasks should be named.\n" +
+		"/tmp/repo/roles/web/tasks/main.yml:12: [E301] Commands should not change things if nothing needs doing\n" +
+		"deploy.yml:3: no-changed-when Commands should not change things if nothing needs doing\n" +
+		"Finished with 3 failure(s)\n"
+
+	annotations := parseLintOutput(output, "/tmp/repo")
+
+	if len(annotations) != 3 {
+		t.Fatal("expected 3 annotations, got", len(annotations))
+	}
+
+	if annotations[0].File != "site.yml" || annotations[0].Line != 5 || annotations[0].Rule != "name[missing]" ||
+		annotations[0].Message != "All tasks should be named." {
+		t.Fatal("unexpected annotation", annotations[0])
+	}
+
+	if annotations[1].File != "roles/web/tasks/main.yml" || annotations[1].Line != 12 || annotations[1].Rule != "E301" {
+		t.Fatal("unexpected annotation", annotations[1])
+	}
+
+	if annotations[2].Rule != "no-changed-when" || annotations[2].Source != db.TaskAnnotationLint {
+		t.Fatal("unexpected annotation", annotations[2])
+	}
+}
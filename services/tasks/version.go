@@ -0,0 +1,77 @@
+package tasks
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ansible-semaphore/semaphore/db"
+	"github.com/ansible-semaphore/semaphore/util"
+)
+
+// getNextVersion returns the version of a new build of the template. lastVersion is
+// the version of the previous build. It returns nil if the version is known only when
+// the task runs.
+func getNextVersion(tpl db.Template, lastVersion *string, now time.Time) *string {
+	var version string
+
+	switch tpl.VersionStrategy {
+	case db.TemplateVersionGitTag:
+		return nil
+	case db.TemplateVersionDate:
+		version = getNextDateVersion(lastVersion, now)
+	case db.TemplateVersionSemverMajor, db.TemplateVersionSemverMinor, db.TemplateVersionSemverPatch:
+		version = getNextSemanticVersion(tpl.GetStartVersion(), lastVersion, tpl.VersionStrategy)
+	default:
+		if lastVersion == nil || tpl.StartVersion == nil {
+			return tpl.StartVersion
+		}
+		version = getNextBuildVersion(*tpl.StartVersion, *lastVersion)
+	}
+
+	return &version
+}
+
+// getNextSemanticVersion bumps the part of the last version defined by the strategy.
+// It returns the start version if the last version precedes it or is not a semantic version.
+func getNextSemanticVersion(startVersion string, lastVersion *string, strategy db.TemplateVersionStrategy) string {
+	start, ok := util.ParseSemanticVersion(startVersion)
+	if !ok || lastVersion == nil {
+		return startVersion
+	}
+
+	last, ok := util.ParseSemanticVersion(*lastVersion)
+	if !ok || last.Less(start) {
+		return startVersion
+	}
+
+	switch strategy {
+	case db.TemplateVersionSemverMajor:
+		last.Major++
+		last.Minor = 0
+		last.Patch = 0
+	case db.TemplateVersionSemverMinor:
+		last.Minor++
+		last.Patch = 0
+	default:
+		last.Patch++
+	}
+
+	last.Prefix = start.Prefix
+
+	return last.String()
+}
+
+// getNextDateVersion returns the date followed by the number of the build of the day.
+func getNextDateVersion(lastVersion *string, now time.Time) string {
+	prefix := now.Format("2006.01.02") + "."
+	n := 1
+
+	if lastVersion != nil && strings.HasPrefix(*lastVersion, prefix) {
+		if last, err := strconv.Atoi((*lastVersion)[len(prefix):]); err == nil {
+			n = last + 1
+		}
+	}
+
+	return prefix + strconv.Itoa(n)
+}
@@ -0,0 +1,129 @@
+package tasks
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/ansible-semaphore/semaphore/db"
+	"github.com/ansible-semaphore/semaphore/util"
+)
+
+func TestGetNextSemanticVersion(t *testing.T) {
+	last := "v1.4.2"
+
+	s := getNextSemanticVersion("v1.0.0", &last, db.TemplateVersionSemverMajor)
+	if s != "v2.0.0" {
+		t.Fatal(s)
+	}
+
+	s = getNextSemanticVersion("v1.0.0", &last, db.TemplateVersionSemverMinor)
+	if s != "v1.5.0" {
+		t.Fatal(s)
+	}
+
+	s = getNextSemanticVersion("v1.0.0", &last, db.TemplateVersionSemverPatch)
+	if s != "v1.4.3" {
+		t.Fatal(s)
+	}
+
+	s = getNextSemanticVersion("2.0.0", &last, db.TemplateVersionSemverPatch)
+	if s != "2.0.0" {
+		t.Fatal("start version must be used if it is greater than the last version, got", s)
+	}
+
+	s = getNextSemanticVersion("1.0.0", nil, db.TemplateVersionSemverPatch)
+	if s != "1.0.0" {
+		t.Fatal(s)
+	}
+}
+
+func TestGetNextDateVersion(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	s := getNextDateVersion(nil, now)
+	if s != "2026.10.17.1" {
+		t.Fatal(s)
+	}
+
+	last := "2026.10.17.4"
+	s = getNextDateVersion(&last, now)
+	if s != "2026.10.17.5" {
+		t.Fatal(s)
+	}
+
+	last = "2026.10.16.4"
+	s = getNextDateVersion(&last, now)
+	if s != "2026.10.17.1" {
+		t.Fatal(s)
+	}
+}
+
+func TestGetNextVersion(t *testing.T) {
+	start := "1.0"
+	last := "1.3"
+
+	v := getNextVersion(db.Template{StartVersion: &start}, &last, time.Now())
+	if v == nil || *v != "1.4" {
+		t.Fatal("unexpected version", v)
+	}
+
+	v = getNextVersion(db.Template{VersionStrategy: db.TemplateVersionGitTag}, &last, time.Now())
+	if v != nil {
+		t.Fatal("git tag versions are known only when the task runs")
+	}
+
+	v = getNextVersion(db.Template{}, nil, time.Now())
+	if v != nil {
+		t.Fatal("unexpected version", *v)
+	}
+}
+
+func TestSetVersionFromGitTag(t *testing.T) {
+	repoPath, err := ioutil.TempDir("", "semaphore_tags_repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(repoPath) //nolint: errcheck
+
+	oldConfig := util.Config
+	util.Config = &util.ConfigType{TmpPath: os.TempDir()}
+	defer func() { util.Config = oldConfig }()
+
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", repoPath, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, gitErr := cmd.CombinedOutput(); gitErr != nil {
+			t.Fatal(gitErr, string(out))
+		}
+	}
+
+	git("init")
+	git("commit", "--allow-empty", "-m", "init")
+
+	logger := &testJobLogger{}
+	tsk := LocalJob{
+		Logger:     logger,
+		Template:   db.Template{Type: db.TemplateBuild, VersionStrategy: db.TemplateVersionGitTag},
+		Repository: db.Repository{GitURL: repoPath, SSHKey: db.AccessKey{Type: db.AccessKeyNone}},
+	}
+
+	if err = tsk.setVersionFromGitTag(); err != nil {
+		t.Fatal("commit without tags must not fail the build: ", err)
+	}
+
+	if tsk.Task.Version != nil || logger.version != nil {
+		t.Fatal("build of a commit without tags must have no version")
+	}
+
+	git("tag", "v1.2.0")
+
+	if err = tsk.setVersionFromGitTag(); err != nil {
+		t.Fatal(err)
+	}
+
+	if tsk.Task.Version == nil || *tsk.Task.Version != "v1.2.0" || *logger.version != "v1.2.0" {
+		t.Fatal("version must be the tag of the commit")
+	}
+}
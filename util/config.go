@@ -49,6 +49,64 @@ type ldapMappings struct {
 	CN   string `json:"cn"`
 }
 
+// ContainerExecutorSettings configures running of tasks of templates with a container image.
+type ContainerExecutorSettings struct {
+	// Engine is the container CLI, docker or podman.
+	Engine string `json:"engine"`
+
+	// Args are additional arguments of the run command, for example --network=host.
+	Args []string `json:"args"`
+}
+
+// GalaxyCacheSettings configures the cache of roles and collections installed by ansible-galaxy.
+type GalaxyCacheSettings struct {
+	// Path is the directory of the cache. Requirements are installed there once for
+	// each distinct requirements.yml and shared by all templates.
+	Path string `json:"path"`
+
+	// MaxAgeDays is number of days after which requirements not used by tasks
+	// are removed from the cache. Zero disables removal. It is 30 if not set.
+	MaxAgeDays *int `json:"max_age_days,omitempty"`
+
+	// Offline makes tasks fail if their requirements are not in the cache
+	// instead of downloading them from Galaxy.
+	Offline bool `json:"offline"`
+}
+
+// RunnerSettings configures the process started by `semaphore runner`.
+type RunnerSettings struct {
+	// APIURL is the address of the Semaphore server API, for example https://semaphore.example.com/api
+	APIURL string `json:"api_url"`
+
+	// RegistrationToken must match runner_registration_token of the server.
+	RegistrationToken string `json:"registration_token"`
+
+	// ConfigFile stores the runner ID and token obtained during registration.
+	ConfigFile string `json:"config_file"`
+
+	// MaxParallelTasks limits number of tasks which the runner runs at the same time.
+	MaxParallelTasks int `json:"max_parallel_tasks"`
+}
+
+// TaskRetentionSettings configures cleanup of old tasks and their output.
+// Projects can override MaxTasksPerTemplate and OutputRetentionDays.
+type TaskRetentionSettings struct {
+	// MaxTasksPerTemplate is number of the latest tasks of every template which are kept.
+	// Older finished tasks are deleted with their output. Zero means tasks are never deleted.
+	MaxTasksPerTemplate int `json:"max_tasks_per_template"`
+
+	// OutputRetentionDays is number of days the output of finished tasks is kept.
+	// Task metadata is kept. Zero means output is kept forever.
+	OutputRetentionDays int `json:"output_retention_days"`
+
+	// Interval is number of minutes between cleanups.
+	Interval int `json:"interval"`
+
+	// CompressOutput enables storing output of finished tasks
+	// as a single compressed record instead of one record per line.
+	CompressOutput bool `json:"compress_output"`
+}
+
 //ConfigType mapping between Config and the json file that sets it
 type ConfigType struct {
 	MySQL    DbConfig `json:"mysql"`
@@ -101,6 +159,30 @@ type ConfigType struct {
 	// task concurrency
 	MaxParallelTasks int `json:"max_parallel_tasks"`
 
+	// TaskStopGracePeriod is number of seconds which processes of a stopped task
+	// have to exit after SIGINT before they are killed.
+	TaskStopGracePeriod int `json:"task_stop_grace_period"`
+
+	// RunnerRegistrationToken is required by remote runners to register on the server.
+	// Registration is disabled if it is empty.
+	RunnerRegistrationToken string `json:"runner_registration_token"`
+
+	// RunnerHeartbeatTimeout is number of seconds after which a task taken by a runner fails
+	// if the runner does not report its progress, for example because it crashed.
+	RunnerHeartbeatTimeout int `json:"runner_heartbeat_timeout"`
+
+	// Runner contains settings used in runner mode.
+	Runner RunnerSettings `json:"runner"`
+
+	ContainerExecutor ContainerExecutorSettings `json:"container_executor"`
+
+	TaskRetention TaskRetentionSettings `json:"task_retention"`
+
+	// ArtifactsPath is the directory where files collected from tasks are stored.
+	ArtifactsPath string `json:"artifacts_path"`
+
+	GalaxyCache GalaxyCacheSettings `json:"galaxy_cache"`
+
 	// configType field ordering with bools at end reduces struct size
 	// (maligned check)
 
@@ -114,6 +196,10 @@ type ConfigType struct {
 	SshConfigPath string `json:"ssh_config_path"`
 
 	DemoMode bool `json:"demo_mode"`
+
+	// UseRemoteRunner makes the server pass tasks to remote runners
+	// instead of running them locally.
+	UseRemoteRunner bool `json:"use_remote_runner"`
 }
 
 //Config exposes the application configuration storage for use in the application
@@ -198,9 +284,42 @@ func validateConfig() {
 		Config.TmpPath = "/tmp/semaphore"
 	}
 
+	if len(Config.ArtifactsPath) == 0 {
+		Config.ArtifactsPath = filepath.Join(Config.TmpPath, "artifacts")
+	}
+
+	if len(Config.GalaxyCache.Path) == 0 {
+		Config.GalaxyCache.Path = filepath.Join(Config.TmpPath, "galaxy_cache")
+	}
+
+	if Config.GalaxyCache.MaxAgeDays == nil {
+		maxAgeDays := 30
+		Config.GalaxyCache.MaxAgeDays = &maxAgeDays
+	}
+
 	if Config.MaxParallelTasks < 1 {
 		Config.MaxParallelTasks = 10
 	}
+
+	if Config.TaskStopGracePeriod < 1 {
+		Config.TaskStopGracePeriod = 10
+	}
+
+	if Config.RunnerHeartbeatTimeout < 1 {
+		Config.RunnerHeartbeatTimeout = 60
+	}
+
+	if Config.Runner.MaxParallelTasks < 1 {
+		Config.Runner.MaxParallelTasks = 1
+	}
+
+	if Config.ContainerExecutor.Engine == "" {
+		Config.ContainerExecutor.Engine = "docker"
+	}
+
+	if Config.TaskRetention.Interval < 1 {
+		Config.TaskRetention.Interval = 60
+	}
 }
 
 func validatePort() {
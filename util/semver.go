@@ -0,0 +1,55 @@
+package util
+
+import (
+	"regexp"
+	"strconv"
+)
+
+var semanticVersionRegex = regexp.MustCompile(`^([^\d]*)(\d+)\.(\d+)\.(\d+)$`)
+
+// SemanticVersion is a MAJOR.MINOR.PATCH version with an optional prefix like "v".
+type SemanticVersion struct {
+	Prefix string
+	Major  int
+	Minor  int
+	Patch  int
+}
+
+// ParseSemanticVersion parses versions like 1.2.3 and v1.2.3.
+func ParseSemanticVersion(str string) (v SemanticVersion, ok bool) {
+	m := semanticVersionRegex.FindStringSubmatch(str)
+	if m == nil {
+		return
+	}
+
+	var err error
+
+	v.Prefix = m[1]
+	if v.Major, err = strconv.Atoi(m[2]); err != nil {
+		return
+	}
+	if v.Minor, err = strconv.Atoi(m[3]); err != nil {
+		return
+	}
+	if v.Patch, err = strconv.Atoi(m[4]); err != nil {
+		return
+	}
+
+	ok = true
+	return
+}
+
+// Less returns true if the version precedes the other version. Prefixes are ignored.
+func (v SemanticVersion) Less(other SemanticVersion) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor < other.Minor
+	}
+	return v.Patch < other.Patch
+}
+
+func (v SemanticVersion) String() string {
+	return v.Prefix + strconv.Itoa(v.Major) + "." + strconv.Itoa(v.Minor) + "." + strconv.Itoa(v.Patch)
+}